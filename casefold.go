@@ -0,0 +1,51 @@
+package ramfs
+
+import (
+	"path"
+	"strings"
+)
+
+// NewCaseInsensitive creates a new Filesystem whose names are case
+// insensitive, like the default filesystems of macOS and Windows: once
+// "File.TXT" exists, "file.txt" refers to the same file. Files keep the
+// casing they were created with, which is what Stat and ReadDir report.
+func NewCaseInsensitive() *Filesystem {
+	fs := New()
+	fs.folded = make(map[string]string)
+	return fs
+}
+
+// canon returns the name under which the file name is stored. For case
+// insensitive filesystems, that is the existing name that only differs in
+// case from name; if there is none, the existing directory name is used
+// for the parent. Otherwise canon returns name unchanged. The caller must
+// hold fs.mu.
+func (fs *Filesystem) canon(name string) string {
+	if fs.folded == nil || name == "." {
+		return name
+	}
+	if k, ok := fs.folded[strings.ToLower(name)]; ok {
+		return k
+	}
+	dir := path.Dir(name)
+	if dir == "." {
+		return name
+	}
+	return path.Join(fs.canon(dir), path.Base(name))
+}
+
+// put stores n under name. The caller must hold fs.mu.
+func (fs *Filesystem) put(name string, n *Node) {
+	fs.files[name] = n
+	if fs.folded != nil {
+		fs.folded[strings.ToLower(name)] = name
+	}
+}
+
+// del removes name. The caller must hold fs.mu.
+func (fs *Filesystem) del(name string) {
+	delete(fs.files, name)
+	if fs.folded != nil {
+		delete(fs.folded, strings.ToLower(name))
+	}
+}
@@ -0,0 +1,85 @@
+package ramfs
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCaseInsensitive(t *testing.T) {
+	fs := NewCaseInsensitive()
+	if err := fs.WriteFile("File.TXT", []byte("hello"), 0644); err != nil {
+		t.Fatalf("WriteFile(File.TXT) = %v, want nil", err)
+	}
+	for _, name := range []string{"File.TXT", "file.txt", "FILE.txt"} {
+		got, err := fs.ReadFile(name)
+		if err != nil || string(got) != "hello" {
+			t.Fatalf("ReadFile(%q) = %q, %v, want %q, nil", name, got, err, "hello")
+		}
+		fi, err := fs.Stat(name)
+		if err != nil {
+			t.Fatalf("Stat(%q) = %v, want nil", name, err)
+		}
+		if fi.Name() != "File.TXT" {
+			t.Fatalf("Stat(%q).Name() = %q, want %q", name, fi.Name(), "File.TXT")
+		}
+	}
+	f, err := fs.OpenFile("file.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile(file.txt) = %v, want nil", err)
+	}
+	f.Write([]byte(" world"))
+	f.Close()
+	entries, err := fs.ReadDir(".")
+	if err != nil {
+		t.Fatalf("ReadDir(.) = %v, want nil", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "File.TXT" {
+		t.Fatalf("ReadDir(.) = %v, want [File.TXT]", entries)
+	}
+	if got, _ := fs.ReadFile("FILE.TXT"); string(got) != "hello world" {
+		t.Fatalf("ReadFile(FILE.TXT) = %q, want %q", got, "hello world")
+	}
+
+	if err := fs.Mkdir("Dir", 0755); err != nil {
+		t.Fatalf("Mkdir(Dir) = %v, want nil", err)
+	}
+	if err := fs.WriteFile("dir/x", nil, 0644); err != nil {
+		t.Fatalf("WriteFile(dir/x) = %v, want nil", err)
+	}
+	if _, err := fs.Stat("Dir/x"); err != nil {
+		t.Fatalf("Stat(Dir/x) = %v, want nil", err)
+	}
+	entries, err = fs.ReadDir("DIR")
+	if err != nil || len(entries) != 1 || entries[0].Name() != "x" {
+		t.Fatalf("ReadDir(DIR) = %v, %v, want [x], nil", entries, err)
+	}
+	if err := fs.Rename("FILE.txt", "renamed"); err != nil {
+		t.Fatalf("Rename(FILE.txt, renamed) = %v, want nil", err)
+	}
+	if fs.Exists("file.txt") {
+		t.Fatalf("Exists(file.txt) = true after rename, want false")
+	}
+	if err := fs.Remove("RENAMED"); err != nil {
+		t.Fatalf("Remove(RENAMED) = %v, want nil", err)
+	}
+}
+
+func TestCaseSensitiveByDefault(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("File.TXT", nil, 0644); err != nil {
+		t.Fatalf("WriteFile(File.TXT) = %v, want nil", err)
+	}
+	if _, err := fs.Stat("file.txt"); !os.IsNotExist(err) {
+		t.Fatalf("Stat(file.txt) = %v, want ErrNotExist", err)
+	}
+}
+
+func TestCaseInsensitiveGlob(t *testing.T) {
+	fs := NewCaseInsensitive()
+	fs.WriteFile("A.txt", nil, 0644)
+	fs.WriteFile("b.TXT", nil, 0644)
+	got, err := fs.Glob("*.txt")
+	if err != nil || len(got) != 2 || got[0] != "A.txt" || got[1] != "b.TXT" {
+		t.Fatalf("Glob(*.txt) = %v, %v, want [A.txt b.TXT], nil", got, err)
+	}
+}
@@ -0,0 +1,31 @@
+package ramfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	fs := New()
+	data := []byte("hello world")
+	if err := fs.WriteFile("foo", data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := fs.Checksum("foo", sha256.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := sha256.Sum256(data); !bytes.Equal(got, want[:]) {
+		t.Fatalf("checksum(foo) = %x, want %x", got, want)
+	}
+	if _, err := fs.Checksum("missing", sha256.New()); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("checksum(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+	if _, err := fs.Checksum(".", sha256.New()); !errors.Is(err, syscall.EISDIR) {
+		t.Fatalf("checksum(.) = %v, want %v", err, syscall.EISDIR)
+	}
+}
@@ -0,0 +1,118 @@
+package ramfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("dir/sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("loop", "loop"); err != nil {
+		t.Fatal(err)
+	}
+	ro, err := fs.OpenFile("foo", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wo, err := fs.OpenFile("foo", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed, err := fs.OpenFile("foo", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	tests := []struct {
+		desc string
+		fn   func() error
+		op   string
+		want error
+	}{
+		{"open missing", func() error { _, err := fs.Open("missing"); return err }, "open", os.ErrNotExist},
+		{"open invalid", func() error { _, err := fs.Open("/foo"); return err }, "open", os.ErrInvalid},
+		{"open exclusive", func() error { _, err := fs.OpenFile("foo", os.O_CREATE|os.O_EXCL, 0644); return err }, "open", os.ErrExist},
+		{"open loop", func() error { _, err := fs.Open("loop"); return err }, "open", syscall.ELOOP},
+		{"open dir for writing", func() error { _, err := fs.OpenFile("dir", os.O_RDWR, 0); return err }, "open", syscall.EISDIR},
+		{"open dir write-only", func() error { _, err := fs.OpenFile("dir", os.O_WRONLY, 0); return err }, "open", syscall.EISDIR},
+		{"create dir", func() error { _, err := fs.Create("dir"); return err }, "open", syscall.EISDIR},
+		{"create in file", func() error { _, err := fs.Create("foo/bar"); return err }, "open", syscall.ENOTDIR},
+		{"create in missing", func() error { _, err := fs.Create("missing/bar"); return err }, "open", os.ErrNotExist},
+		{"stat missing", func() error { _, err := fs.Stat("missing"); return err }, "stat", os.ErrNotExist},
+		{"lstat missing", func() error { _, err := fs.Lstat("missing"); return err }, "lstat", os.ErrNotExist},
+		{"readfile dir", func() error { _, err := fs.ReadFile("dir"); return err }, "read", syscall.EISDIR},
+		{"readdir file", func() error { _, err := fs.ReadDir("foo"); return err }, "readdir", syscall.ENOTDIR},
+		{"readlink file", func() error { _, err := fs.Readlink("foo"); return err }, "readlink", os.ErrInvalid},
+		{"mkdir exists", func() error { return fs.Mkdir("dir", 0755) }, "mkdir", os.ErrExist},
+		{"mkdir missing parent", func() error { return fs.Mkdir("missing/dir", 0755) }, "mkdir", os.ErrNotExist},
+		{"mkdirall file", func() error { return fs.MkdirAll("foo/dir", 0755) }, "mkdir", syscall.ENOTDIR},
+		{"chmod missing", func() error { return fs.Chmod("missing", 0644) }, "chmod", os.ErrNotExist},
+		{"chown missing", func() error { return fs.Chown("missing", 0, 0) }, "chown", os.ErrNotExist},
+		{"chtimes missing", func() error { return fs.Chtimes("missing", ro.node.ModTime, ro.node.ModTime) }, "chtimes", os.ErrNotExist},
+		{"remove missing", func() error { return fs.Remove("missing") }, "remove", os.ErrNotExist},
+		{"remove non-empty", func() error { return fs.Remove("dir") }, "remove", syscall.ENOTEMPTY},
+		{"truncate missing", func() error { return fs.Truncate("missing", 0) }, "truncate", os.ErrNotExist},
+		{"truncate dir", func() error { return fs.Truncate("dir", 0) }, "truncate", syscall.EISDIR},
+		{"file read write-only", func() error { _, err := wo.Read(make([]byte, 1)); return err }, "read", os.ErrPermission},
+		{"file write read-only", func() error { _, err := ro.Write([]byte("x")); return err }, "write", os.ErrPermission},
+		{"file writeat negative", func() error { _, err := wo.WriteAt([]byte("x"), -1); return err }, "writeat", os.ErrInvalid},
+		{"file readat negative", func() error { _, err := ro.ReadAt(make([]byte, 1), -1); return err }, "readat", os.ErrInvalid},
+		{"file seek negative", func() error { _, err := ro.Seek(-1, io.SeekStart); return err }, "seek", os.ErrInvalid},
+		{"file seek whence", func() error { _, err := ro.Seek(0, 42); return err }, "seek", os.ErrInvalid},
+		{"file truncate negative", func() error { return wo.Truncate(-1) }, "truncate", os.ErrInvalid},
+		{"file readdir file", func() error { _, err := ro.ReadDir(0); return err }, "readdir", syscall.ENOTDIR},
+		{"file read closed", func() error { _, err := closed.Read(make([]byte, 1)); return err }, "read", os.ErrClosed},
+		{"file close closed", closed.Close, "close", os.ErrClosed},
+	}
+	for _, tc := range tests {
+		err := tc.fn()
+		var perr *os.PathError
+		if !errors.As(err, &perr) {
+			t.Errorf("%s: err = %#v, want *os.PathError", tc.desc, err)
+			continue
+		}
+		if perr.Op != tc.op {
+			t.Errorf("%s: op = %q, want %q", tc.desc, perr.Op, tc.op)
+		}
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.desc, err, tc.want)
+		}
+	}
+
+	linkTests := []struct {
+		desc string
+		fn   func() error
+		op   string
+		want error
+	}{
+		{"rename missing", func() error { return fs.Rename("missing", "bar") }, "rename", os.ErrNotExist},
+		{"rename invalid", func() error { return fs.Rename("foo", "../bar") }, "rename", os.ErrInvalid},
+		{"link exists", func() error { return fs.Link("foo", "foo") }, "link", os.ErrExist},
+		{"link dir", func() error { return fs.Link("dir", "bar") }, "link", syscall.EPERM},
+		{"symlink exists", func() error { return fs.Symlink("foo", "foo") }, "symlink", os.ErrExist},
+		{"move missing parent", func() error { return fs.Move("foo", "missing/foo") }, "move", os.ErrNotExist},
+	}
+	for _, tc := range linkTests {
+		err := tc.fn()
+		var lerr *os.LinkError
+		if !errors.As(err, &lerr) {
+			t.Errorf("%s: err = %#v, want *os.LinkError", tc.desc, err)
+			continue
+		}
+		if lerr.Op != tc.op {
+			t.Errorf("%s: op = %q, want %q", tc.desc, lerr.Op, tc.op)
+		}
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.desc, err, tc.want)
+		}
+	}
+}
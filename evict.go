@@ -0,0 +1,67 @@
+package ramfs
+
+import (
+	"sort"
+	"time"
+)
+
+// EvictLRU removes the least recently accessed regular files until the
+// data of the remaining files takes up at most targetBytes, as reported
+// by Usage. Files are ordered by their access time, ties are broken by
+// name. Directories and symbolic links are never removed. EvictLRU
+// returns the number of files removed.
+func (fs *Filesystem) EvictLRU(targetBytes int64) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	type candidate struct {
+		name  string
+		node  *Node
+		atime time.Time
+	}
+	var (
+		candidates []candidate
+		total      int64
+		names      = make(map[*Node]int)
+	)
+	for k, f := range fs.files {
+		if names[f] == 0 {
+			f.Mu.RLock()
+			total += int64(f.Data.Len())
+			f.Mu.RUnlock()
+		}
+		names[f]++
+		if !f.Mode.IsRegular() {
+			continue
+		}
+		f.atimeMu.Lock()
+		atime := f.AccessTime
+		f.atimeMu.Unlock()
+		candidates = append(candidates, candidate{k, f, atime})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].atime.Equal(candidates[j].atime) {
+			return candidates[i].atime.Before(candidates[j].atime)
+		}
+		return candidates[i].name < candidates[j].name
+	})
+	evicted := 0
+	for _, c := range candidates {
+		if total <= targetBytes {
+			break
+		}
+		// The data is only gone once the last name of the file is.
+		if names[c.node]--; names[c.node] == 0 {
+			c.node.Mu.RLock()
+			total -= int64(c.node.Data.Len())
+			c.node.Mu.RUnlock()
+		}
+		fs.del(c.name)
+		c.node.unlink()
+		fs.emit(c.name, Remove)
+		if fs.observer != nil {
+			fs.observer.OnRemove(c.name)
+		}
+		evicted++
+	}
+	return evicted
+}
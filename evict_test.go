@@ -0,0 +1,70 @@
+package ramfs
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvictLRU(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)}
+	fs := New()
+	fs.SetClock(clock)
+	if err := fs.Mkdir("dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "dir/d"} {
+		clock.now = clock.now.Add(time.Second)
+		if err := fs.WriteFile(name, make([]byte, 10), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Reading a and c makes b the least recently used file.
+	for _, name := range []string{"a", "c"} {
+		clock.now = clock.now.Add(time.Second)
+		if _, err := fs.ReadFile(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := fs.EvictLRU(40); n != 0 {
+		t.Fatalf("evictlru(40) = %d, want 0", n)
+	}
+	if n := fs.EvictLRU(20); n != 2 {
+		t.Fatalf("evictlru(20) = %d, want 2", n)
+	}
+	var left []string
+	fs.Range(func(name string, info os.FileInfo) bool {
+		left = append(left, name)
+		return true
+	})
+	if want := []string{"a", "c", "dir"}; !reflect.DeepEqual(left, want) {
+		t.Fatalf("files = %q after evictlru(20), want %q", left, want)
+	}
+	if _, bytes := fs.Usage(); bytes != 20 {
+		t.Fatalf("usage() = %d bytes, want 20", bytes)
+	}
+	if n := fs.EvictLRU(0); n != 2 {
+		t.Fatalf("evictlru(0) = %d, want 2", n)
+	}
+	if _, err := fs.Stat("dir"); err != nil {
+		t.Fatalf("stat(dir) = %v after evictlru(0), want nil", err)
+	}
+}
+
+func TestEvictLRULinks(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("a", make([]byte, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Link("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	// Both names have to go before the data is released.
+	if n := fs.EvictLRU(0); n != 2 {
+		t.Fatalf("evictlru(0) = %d, want 2", n)
+	}
+	if files, bytes := fs.Usage(); files != 0 || bytes != 0 {
+		t.Fatalf("usage() = %d, %d, want 0, 0", files, bytes)
+	}
+}
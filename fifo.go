@@ -0,0 +1,45 @@
+package ramfs
+
+import (
+	"io"
+	"os"
+	"sync"
+)
+
+// CreateFIFO creates the named file as a FIFO, also known as a named
+// pipe, and returns it opened for writing. Data written to a FIFO is
+// consumed by reading it: Reads block until another File writes to it and
+// return io.EOF once the FIFO is empty and no File has it open for writing
+// anymore. Readers open the FIFO with Open or OpenFile. FIFOs do not
+// support Seek, ReadAt or WriteAt. CreateFIFO fails if name exists.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) CreateFIFO(name string) (*File, error) {
+	f, err := fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.ModeNamedPipe|0666)
+	if err != nil {
+		return nil, err
+	}
+	f.node.Mu.Lock()
+	f.node.fifo = sync.NewCond(&f.node.Mu)
+	f.node.writers = 1
+	f.node.Mu.Unlock()
+	return f, nil
+}
+
+// readFIFO reads and consumes up to len(p) bytes from a FIFO, waiting for
+// data as long as there are writers.
+func (f *File) readFIFO(p []byte) (int, error) {
+	n := f.node
+	n.Mu.Lock()
+	defer n.Mu.Unlock()
+	for n.Data.Len() == 0 {
+		if n.writers <= 0 {
+			return 0, io.EOF
+		}
+		n.fifo.Wait()
+	}
+	n.touch()
+	m, _ := n.Data.Read(p)
+	n.grow(-m)
+	f.observeRead(m)
+	return m, nil
+}
@@ -0,0 +1,128 @@
+package ramfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestFIFO(t *testing.T) {
+	fs := New()
+	w, err := fs.CreateFIFO("pipe")
+	if err != nil {
+		t.Fatalf("createfifo(pipe) = %v", err)
+	}
+	st, err := fs.Stat("pipe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode()&os.ModeNamedPipe == 0 {
+		t.Fatalf("stat(pipe).Mode() = %v, want named pipe", st.Mode())
+	}
+	r, err := fs.OpenFile("pipe", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	// Every record is only written once the previous one has been
+	// read, so the consumer has to block for each of them.
+	ack := make(chan struct{})
+	var want strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&want, "record %d\n", i)
+	}
+	go func() {
+		defer w.Close()
+		for i := 0; i < 10; i++ {
+			if _, err := fmt.Fprintf(w, "record %d\n", i); err != nil {
+				t.Errorf("write() = %v", err)
+				return
+			}
+			<-ack
+		}
+	}()
+	var got strings.Builder
+	b := make([]byte, 64)
+	for {
+		n, err := r.Read(b)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read() = %v", err)
+		}
+		got.Write(b[:n])
+		ack <- struct{}{}
+	}
+	if got.String() != want.String() {
+		t.Fatalf("read() = %q, want %q", got.String(), want.String())
+	}
+	if _, bytes := fs.Usage(); bytes != 0 {
+		t.Fatalf("usage() = %d bytes after reading everything, want 0", bytes)
+	}
+}
+
+func TestFIFOCopy(t *testing.T) {
+	fs := New()
+	w, err := fs.CreateFIFO("pipe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := fs.OpenFile("pipe", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	want := strings.Repeat("hello world ", 1000)
+	go func() {
+		defer w.Close()
+		if _, err := io.Copy(w, strings.NewReader(want)); err != nil {
+			t.Errorf("copy() = %v", err)
+		}
+	}()
+	var got strings.Builder
+	if _, err := io.Copy(&got, r); err != nil {
+		t.Fatalf("copy() = %v", err)
+	}
+	if got.String() != want {
+		t.Fatalf("copy() = %d bytes, want %d", got.Len(), len(want))
+	}
+}
+
+func TestFIFOErrors(t *testing.T) {
+	fs := New()
+	w, err := fs.CreateFIFO("pipe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := fs.CreateFIFO("pipe"); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("createfifo(pipe) twice = %v, want %v", err, os.ErrExist)
+	}
+	if _, err := w.Seek(0, io.SeekStart); !errors.Is(err, syscall.ESPIPE) {
+		t.Fatalf("seek() = %v, want %v", err, syscall.ESPIPE)
+	}
+	if _, err := w.WriteAt([]byte("x"), 0); !errors.Is(err, syscall.ESPIPE) {
+		t.Fatalf("writeat() = %v, want %v", err, syscall.ESPIPE)
+	}
+	r, err := fs.OpenFile("pipe", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.ReadAt(make([]byte, 1), 0); !errors.Is(err, syscall.ESPIPE) {
+		t.Fatalf("readat() = %v, want %v", err, syscall.ESPIPE)
+	}
+	rc, err := fs.Reader("pipe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rc.(*File); !ok {
+		t.Fatalf("reader(pipe) = %T, want *File", rc)
+	}
+	rc.Close()
+}
@@ -2,21 +2,74 @@ package ramfs
 
 import (
 	"bytes"
-	"fmt"
+	"context"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
 // Node represents a single File in the filesystem
 type Node struct {
-	Mu      sync.Mutex
+	Mu      sync.RWMutex
 	Data    bytes.Buffer
 	Name    string
 	Mode    os.FileMode
 	ModTime time.Time
 	IsDir   bool
+	// Target is the destination of a symbolic link.
+	Target string
+	// Uid and Gid are the numeric ids of the owner and group.
+	Uid int
+	Gid int
+
+	// lockMu guards locker, the File holding the advisory lock of the
+	// node, and unlocked, which is closed when the lock is released.
+	lockMu   sync.Mutex
+	locker   *File
+	unlocked chan struct{}
+
+	// ino is the inode number of the node, unique within its filesystem.
+	ino uint64
+	// nlink is the number of names referring to the node and handles
+	// the number of open Files. Both are only changed while holding the
+	// mutex of the filesystem; the data is released once both drop to
+	// zero. nlink is atomic so that Stat can read it without that mutex.
+	nlink   atomic.Int32
+	handles int
+
+	// AccessTime is guarded by atimeMu rather than Mu, so that readers
+	// holding only a read lock can update it.
+	atimeMu    sync.Mutex
+	AccessTime time.Time
+
+	fs *Filesystem
+	// cow is set if Data may share its backing array with another node
+	// and has to be copied before it is modified.
+	cow bool
+	// ring is the capacity of a ring buffer file, see CreateRing. It is
+	// zero for regular files.
+	ring int
+	// fifo is signalled when data is written to a FIFO or its last writer
+	// goes away, see CreateFIFO. It is nil for other files and never
+	// changes once set. writers is the number of Files open for writing
+	// a FIFO and guarded by Mu.
+	fifo    *sync.Cond
+	writers int
+}
+
+// SysInfo holds additional information about a file. It is returned by
+// FileInfo.Sys.
+type SysInfo struct {
+	AccessTime time.Time
+	Ino        uint64
+	Nlink      uint64
+	Uid        int
+	Gid        int
 }
 
 // FileInfo holds information about the file
@@ -26,6 +79,7 @@ type FileInfo struct {
 	mode    os.FileMode
 	modTime time.Time
 	isDir   bool
+	sys     *SysInfo
 }
 
 // Name of the file
@@ -53,112 +107,710 @@ func (f *FileInfo) IsDir() bool {
 	return f.isDir
 }
 
-// Sys returns nil
+// Sys returns a *SysInfo
 func (f *FileInfo) Sys() interface{} {
-	return nil
+	return f.sys
+}
+
+// DirEntry is an entry read from a directory. It refers to the Node of
+// the entry, so Info reports the state of the file at the time Info is
+// called rather than when the directory was read.
+type DirEntry struct {
+	node *Node
+	name string
+}
+
+// Name of the file
+func (d *DirEntry) Name() string {
+	return path.Base(d.name)
+}
+
+// IsDir whether the file is a directory
+func (d *DirEntry) IsDir() bool {
+	return d.Type().IsDir()
+}
+
+// Type returns the type bits of the mode of the file
+func (d *DirEntry) Type() fs.FileMode {
+	d.node.Mu.RLock()
+	defer d.node.Mu.RUnlock()
+	return d.node.Mode.Type()
+}
+
+// Info returns the FileInfo of the file
+func (d *DirEntry) Info() (fs.FileInfo, error) {
+	return d.node.stat(d.name), nil
+}
+
+// String returns the entry formatted by io/fs.FormatDirEntry
+func (d *DirEntry) String() string {
+	return fs.FormatDirEntry(d)
 }
 
 // Stat returns the FileInfo of the file
 func (n *Node) Stat() os.FileInfo {
-	n.Mu.Lock()
-	defer n.Mu.Unlock()
+	return n.stat(n.Name)
+}
+
+// stat is like Stat but reports the node under name, which differs from
+// n.Name for hard links.
+func (n *Node) stat(name string) *FileInfo {
+	n.Mu.RLock()
+	defer n.Mu.RUnlock()
+	n.atimeMu.Lock()
+	defer n.atimeMu.Unlock()
+	size := int64(n.Data.Len())
+	if n.Mode&os.ModeSymlink != 0 {
+		size = int64(len(n.Target))
+	}
 	return &FileInfo{
-		name:    n.Name,
-		len:     int64(n.Data.Len()),
+		name:    path.Base(name),
+		len:     size,
 		isDir:   n.IsDir,
 		modTime: n.ModTime,
 		mode:    n.Mode,
+		sys: &SysInfo{
+			AccessTime: n.AccessTime,
+			Ino:        n.ino,
+			Nlink:      uint64(n.nlink.Load()),
+			Uid:        n.Uid,
+			Gid:        n.Gid,
+		},
+	}
+}
+
+// clone returns a copy of the node belonging to fs that does not share
+// any data with n.
+func (n *Node) clone(fs *Filesystem) *Node {
+	n.Mu.RLock()
+	defer n.Mu.RUnlock()
+	n.atimeMu.Lock()
+	defer n.atimeMu.Unlock()
+	c := &Node{
+		Name:       n.Name,
+		Mode:       n.Mode,
+		ModTime:    n.ModTime,
+		IsDir:      n.IsDir,
+		Target:     n.Target,
+		Uid:        n.Uid,
+		Gid:        n.Gid,
+		AccessTime: n.AccessTime,
+		ino:        n.ino,
+		fs:         fs,
+		ring:       n.ring,
+	}
+	c.nlink.Store(n.nlink.Load())
+	c.Data.Write(n.Data.Bytes())
+	return c
+}
+
+// snapshot returns a copy of the node belonging to fs that shares its
+// data with n until either of them is modified.
+func (n *Node) snapshot(fs *Filesystem) *Node {
+	n.Mu.Lock()
+	defer n.Mu.Unlock()
+	n.atimeMu.Lock()
+	defer n.atimeMu.Unlock()
+	n.cow = true
+	d := n.Data.Bytes()
+	c := &Node{
+		Data:       *bytes.NewBuffer(d[:len(d):len(d)]),
+		Name:       n.Name,
+		Mode:       n.Mode,
+		ModTime:    n.ModTime,
+		IsDir:      n.IsDir,
+		Target:     n.Target,
+		Uid:        n.Uid,
+		Gid:        n.Gid,
+		AccessTime: n.AccessTime,
+		ino:        n.ino,
+		fs:         fs,
+		cow:        true,
+		ring:       n.ring,
+	}
+	c.nlink.Store(n.nlink.Load())
+	return c
+}
+
+// unshare copies the data of the node if it may be shared with another
+// node. The caller must hold n.Mu.
+func (n *Node) unshare() {
+	if !n.cow {
+		return
 	}
+	d := append([]byte(nil), n.Data.Bytes()...)
+	n.Data = *bytes.NewBuffer(d)
+	n.cow = false
+}
+
+// now returns the current time of the filesystem clock.
+func (n *Node) now() time.Time {
+	if n.fs == nil {
+		return time.Now()
+	}
+	return n.fs.clock.Now()
+}
+
+// touch sets the access time of the node to now.
+func (n *Node) touch() {
+	n.atimeMu.Lock()
+	n.AccessTime = n.now()
+	n.atimeMu.Unlock()
 }
 
 // File is used to read and write to. The API should mirror the one for the os.File.
+// Files opened for the same file share its data, but each of them has its
+// own offset.
 type File struct {
 	node   *Node
+	name   string
 	offset int
+	flag   int
+	dir    []fs.DirEntry
+	closed bool
+	// ctx is the context passed to OpenContext, if any.
+	ctx context.Context
+}
+
+// Name returns the name of the file as presented to OpenFile.
+func (f *File) Name() string {
+	if f.name == "" {
+		return f.node.Name
+	}
+	return f.name
 }
 
-// Truncate truncates the file
+// checkValid returns an error if the file has been closed.
+func (f *File) checkValid(op string) error {
+	if f.closed {
+		return &os.PathError{
+			Op:   op,
+			Path: f.Name(),
+			Err:  os.ErrClosed,
+		}
+	}
+	return nil
+}
+
+// checkRead returns an error if the file is closed or was not opened for
+// reading.
+func (f *File) checkRead(op string) error {
+	if err := f.checkValid(op); err != nil {
+		return err
+	}
+	if f.flag&(os.O_RDONLY|os.O_WRONLY|os.O_RDWR) == os.O_WRONLY {
+		return &os.PathError{
+			Op:   op,
+			Path: f.Name(),
+			Err:  os.ErrPermission,
+		}
+	}
+	return nil
+}
+
+// checkWrite returns an error if the file is closed or was not opened
+// for writing.
+func (f *File) checkWrite(op string) error {
+	if err := f.checkValid(op); err != nil {
+		return err
+	}
+	if f.flag&(os.O_RDONLY|os.O_WRONLY|os.O_RDWR) == os.O_RDONLY {
+		return &os.PathError{
+			Op:   op,
+			Path: f.Name(),
+			Err:  os.ErrPermission,
+		}
+	}
+	return nil
+}
+
+// checkOverwrite returns an error if the file is not open for writing or
+// was opened with O_APPEND, in which case existing data must not change.
+func (f *File) checkOverwrite(op string) error {
+	if err := f.checkWrite(op); err != nil {
+		return err
+	}
+	if f.flag&os.O_APPEND != 0 {
+		return &os.PathError{
+			Op:   op,
+			Path: f.Name(),
+			Err:  os.ErrPermission,
+		}
+	}
+	return nil
+}
+
+// Grow grows the capacity of the file, if necessary, so that another n
+// bytes can be written without reallocating its data. It does not change
+// the size of the file and does nothing if n is negative or the file is
+// not open for writing.
+func (f *File) Grow(n int) {
+	if n < 0 || f.checkWrite("grow") != nil {
+		return
+	}
+	f.node.Mu.Lock()
+	defer f.node.Mu.Unlock()
+	f.node.unshare()
+	f.node.Data.Grow(n)
+}
+
+// Truncate changes the size of the file. If the file grows, the new
+// bytes are zero. It does not change the offset. Files opened with
+// O_APPEND cannot be truncated.
+// If there is an error, it will be of type *PathError.
 func (f *File) Truncate(n int64) error {
+	if err := f.checkOverwrite("truncate"); err != nil {
+		return err
+	}
+	if n < 0 {
+		return &os.PathError{
+			Op:   "truncate",
+			Path: f.Name(),
+			Err:  os.ErrInvalid,
+		}
+	}
 	f.node.Mu.Lock()
 	defer f.node.Mu.Unlock()
-	f.node.Data.Truncate(int(n))
+	if err := f.node.truncate(int(n)); err != nil {
+		return err
+	}
+	f.node.emit(f.Name(), Write)
+	return nil
+}
+
+// truncate shrinks or zero-extends the data to n bytes. The caller must
+// hold n.Mu.
+func (n *Node) truncate(size int) error {
+	if !n.grow(size - n.Data.Len()) {
+		return &os.PathError{
+			Op:   "truncate",
+			Path: n.Name,
+			Err:  syscall.ENOSPC,
+		}
+	}
+	n.unshare()
+	n.ModTime = n.now()
+	if size <= n.Data.Len() {
+		n.Data.Truncate(size)
+		return nil
+	}
+	n.Data.Write(make([]byte, size-n.Data.Len()))
+	n.trim()
 	return nil
 }
 
-// Write writes the content of the array into the file.
+// unlink drops one of the names referring to the node and releases its
+// data once neither names nor open Files are left. The caller must hold the mutex of the
+// filesystem.
+func (n *Node) unlink() {
+	if n.nlink.Add(-1) <= 0 && n.handles <= 0 {
+		n.release()
+	}
+}
+
+// closeHandle drops one of the open Files referring to the node and
+// releases its data if the node has been removed and no Files are left.
+func (n *Node) closeHandle() {
+	if n.fs == nil {
+		return
+	}
+	n.fs.mu.Lock()
+	defer n.fs.mu.Unlock()
+	if n.handles <= 0 {
+		return
+	}
+	n.handles--
+	if n.nlink.Load() <= 0 && n.handles <= 0 {
+		n.release()
+	}
+}
+
+// release removes the data of the node from the accounting of its
+// filesystem.
+func (n *Node) release() {
+	n.Mu.Lock()
+	n.grow(-n.Data.Len())
+	n.Mu.Unlock()
+}
+
+// grow accounts for the data of the node changing by delta bytes. It
+// reports false if that would exceed the quota of the filesystem or the
+// maximum file size. The caller must hold n.Mu.
+func (n *Node) grow(delta int) bool {
+	if n.fs == nil {
+		return true
+	}
+	if max := n.fs.maxFileSize.Load(); delta > 0 && max > 0 && int64(n.Data.Len()+delta) > max {
+		return false
+	}
+	return n.fs.reserve(int64(delta))
+}
+
+// Write writes the content of the array into the file. Concurrent writes
+// to the same file, even through different Files, are serialized; each
+// holds the lock of the file only for a single copy of p, plus growing
+// the data when appending.
 func (f *File) Write(p []byte) (int, error) {
+	if err := f.checkWrite("write"); err != nil {
+		return 0, err
+	}
+	n, err := f.write(p)
+	f.observeWrite(n)
+	if err == nil {
+		f.node.emit(f.Name(), Write)
+	}
+	return n, err
+}
+
+// write writes p at the offset of the file, or at the end of the data if
+// the file is opened with O_APPEND, is a ring or a FIFO.
+func (f *File) write(p []byte) (int, error) {
 	f.node.Mu.Lock()
 	defer f.node.Mu.Unlock()
-	d := f.node.Data.Bytes()
-	wrote := 0
-	for ; f.offset < len(d); f.offset++ {
-		if wrote >= len(p) {
+	if f.flag&os.O_APPEND != 0 || f.node.ring > 0 || f.node.fifo != nil {
+		f.offset = f.node.Data.Len()
+	}
+	n, err := f.node.writeAt(p, f.offset)
+	f.offset += n
+	if f.node.ring > 0 {
+		f.offset = f.node.Data.Len()
+	}
+	if f.node.fifo != nil {
+		f.node.fifo.Broadcast()
+	}
+	return n, err
+}
+
+// WriteString is like Write, but writes the contents of string s rather
+// than a slice of bytes.
+func (f *File) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+// ReadFrom reads data from r until EOF and writes it to the file at the
+// current offset, overwriting existing bytes before appending. r may
+// block, so it is read into a buffer without holding the lock of the file
+// and only every chunk is written under it, like Write does.
+func (f *File) ReadFrom(r io.Reader) (int64, error) {
+	if err := f.checkWrite("write"); err != nil {
+		return 0, err
+	}
+	buf := make([]byte, 32<<10)
+	var total int64
+	for {
+		m, rerr := r.Read(buf)
+		if m > 0 {
+			n, err := f.write(buf[:m])
+			total += int64(n)
+			f.observeWrite(n)
+			if err != nil {
+				return total, err
+			}
+		}
+		if rerr == io.EOF {
 			break
 		}
-		d[f.offset] = p[wrote]
-		wrote++
+		if rerr != nil {
+			return total, rerr
+		}
 	}
-	n, err := f.node.Data.Write(p[wrote:])
-	f.offset += n
-	return n + wrote, err
+	f.node.emit(f.Name(), Write)
+	return total, nil
 }
 
-// Read reads the content from the file.
-func (f *File) Read(p []byte) (int, error) {
+// WriteAt writes len(p) bytes to the File starting at byte offset off.
+// It does not change the offset of the file. If off is beyond the end
+// of the file, the gap is filled with zero bytes. Files opened with
+// O_APPEND only allow appending writes, so WriteAt fails for them.
+func (f *File) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.checkOverwrite("writeat"); err != nil {
+		return 0, err
+	}
+	if f.node.fifo != nil {
+		return 0, &os.PathError{
+			Op:   "writeat",
+			Path: f.Name(),
+			Err:  syscall.ESPIPE,
+		}
+	}
+	if off < 0 {
+		return 0, &os.PathError{
+			Op:   "writeat",
+			Path: f.Name(),
+			Err:  os.ErrInvalid,
+		}
+	}
 	f.node.Mu.Lock()
 	defer f.node.Mu.Unlock()
-	d := f.node.Data.Bytes()
-	if f.offset >= len(d) {
+	n, err := f.node.writeAt(p, int(off))
+	f.observeWrite(n)
+	if err == nil {
+		f.node.emit(f.Name(), Write)
+	}
+	return n, err
+}
+
+// writeAt overwrites the data starting at off and appends whatever
+// does not fit. If off is beyond the end of the data, the gap is filled
+// with zero bytes first. Writing no data does not fill the gap, like
+// with os.File. The caller must hold n.Mu.
+func (n *Node) writeAt(p []byte, off int) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if end := off + len(p); end > n.Data.Len() && !n.grow(end-n.Data.Len()) {
 		return 0, &os.PathError{
-			Op:   "read",
-			Path: f.node.Name,
-			Err:  io.EOF,
+			Op:   "write",
+			Path: n.Name,
+			Err:  syscall.ENOSPC,
 		}
 	}
-	n := len(p)
-	if f.offset+n > len(d) {
-		n = len(d) - f.offset
+	n.unshare()
+	n.ModTime = n.now()
+	if gap := off - n.Data.Len(); gap > 0 {
+		n.Data.Write(make([]byte, gap))
 	}
-	for i := range p {
-		if i >= n {
-			break
+	wrote := copy(n.Data.Bytes()[off:], p)
+	m, err := n.Data.Write(p[wrote:])
+	n.trim()
+	return m + wrote, err
+}
+
+// Read reads up to len(p) bytes from the file. A short read returns the
+// bytes that were available and a nil error; io.EOF is only returned once
+// no data is left. Reading into an empty p returns 0, nil at any offset.
+func (f *File) Read(p []byte) (int, error) {
+	if err := f.checkRead("read"); err != nil {
+		return 0, err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if f.node.fifo != nil {
+		return f.readFIFO(p)
+	}
+	f.node.Mu.RLock()
+	defer f.node.Mu.RUnlock()
+	f.node.touch()
+	d := f.node.Data.Bytes()
+	if f.offset >= len(d) {
+		return 0, io.EOF
+	}
+	n := copy(p, d[f.offset:])
+	f.offset += n
+	f.observeRead(n)
+	return n, nil
+}
+
+// ReadAt reads len(p) bytes from the file starting at byte offset off.
+// It does not change the offset of the file. ReadAt returns io.EOF when
+// fewer than len(p) bytes are available.
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+	if err := f.checkRead("readat"); err != nil {
+		return 0, err
+	}
+	if f.node.fifo != nil {
+		return 0, &os.PathError{
+			Op:   "readat",
+			Path: f.Name(),
+			Err:  syscall.ESPIPE,
 		}
-		p[i] = d[f.offset]
-		f.offset++
 	}
-	if len(p) != n {
-		return n, &os.PathError{
-			Op:   "read",
-			Path: f.node.Name,
-			Err:  io.EOF,
+	if off < 0 {
+		return 0, &os.PathError{
+			Op:   "readat",
+			Path: f.Name(),
+			Err:  os.ErrInvalid,
 		}
 	}
+	f.node.Mu.RLock()
+	defer f.node.Mu.RUnlock()
+	f.node.touch()
+	d := f.node.Data.Bytes()
+	if off >= int64(len(d)) {
+		return 0, io.EOF
+	}
+	n := copy(p, d[off:])
+	f.observeRead(n)
+	if n < len(p) {
+		return n, io.EOF
+	}
 	return n, nil
 }
 
+// WriteTo writes the remaining content of the file to w in a single call,
+// advancing the offset. It implements io.WriterTo so io.Copy can skip its
+// intermediate buffer.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	if err := f.checkRead("read"); err != nil {
+		return 0, err
+	}
+	if f.node.fifo != nil {
+		// Hide WriteTo so that every chunk goes through Read.
+		return io.Copy(w, struct{ io.Reader }{f})
+	}
+	// w may write to the same file, so it must not be called with the
+	// lock held. Share the data like Reader does instead of copying it.
+	f.node.Mu.Lock()
+	f.node.touch()
+	d := f.node.Data.Bytes()
+	if f.offset >= len(d) {
+		f.node.Mu.Unlock()
+		return 0, nil
+	}
+	f.node.cow = true
+	d = d[f.offset:len(d):len(d)]
+	f.node.Mu.Unlock()
+	n, err := w.Write(d)
+	f.offset += n
+	f.observeRead(n)
+	return int64(n), err
+}
+
 // Seek sets the offset for the next Read or Write on file to offset,
-// interpreted according to whence: 0 means relative to the origin of the file,
-// 1 means relative to the current offset, and 2 means relative to the end.
-// It returns the new offset and an error, if any.
+// interpreted according to whence: io.SeekStart means relative to the origin
+// of the file, io.SeekCurrent means relative to the current offset, and
+// io.SeekEnd means relative to the end. Seeking past the end is allowed:
+// Read then returns io.EOF, while the next Write fills the gap with zero
+// bytes. It returns the new offset and an error, if any.
 func (f *File) Seek(offset int64, whence int) (ret int64, err error) {
+	if err := f.checkValid("seek"); err != nil {
+		return 0, err
+	}
+	if f.node.fifo != nil {
+		return 0, &os.PathError{
+			Op:   "seek",
+			Path: f.Name(),
+			Err:  syscall.ESPIPE,
+		}
+	}
+	var off int
 	switch whence {
-	case 0:
-		f.offset = int(offset)
-	case 1:
-		f.offset += int(offset)
+	case io.SeekStart:
+		off = int(offset)
+	case io.SeekCurrent:
+		off = f.offset + int(offset)
+	case io.SeekEnd:
+		f.node.Mu.RLock()
+		off = f.node.Data.Len() + int(offset)
+		f.node.Mu.RUnlock()
 	default:
-		return int64(f.offset), fmt.Errorf("seek %d not implemented", whence)
+		return int64(f.offset), &os.PathError{
+			Op:   "seek",
+			Path: f.Name(),
+			Err:  os.ErrInvalid,
+		}
+	}
+	if off < 0 {
+		return int64(f.offset), &os.PathError{
+			Op:   "seek",
+			Path: f.Name(),
+			Err:  os.ErrInvalid,
+		}
 	}
+	f.offset = off
 	return int64(f.offset), nil
 }
 
 // Stat returns the FileInfo structure describing file.
 // If there is an error, it will be of type *PathError.
 func (f *File) Stat() (os.FileInfo, error) {
+	if err := f.checkValid("stat"); err != nil {
+		return nil, err
+	}
 	return f.node.Stat(), nil
 }
 
-// Close closes the file
+// Sync commits the current contents of the file to stable storage. As
+// the filesystem only lives in memory, there is nothing to commit and Sync
+// only fails if the file has been closed.
+// If there is an error, it will be of type *PathError.
+func (f *File) Sync() error {
+	return f.checkValid("sync")
+}
+
+// Flush writes any buffered data to the file. Writes are never buffered,
+// so like Sync, Flush only fails if the file has been closed.
+// If there is an error, it will be of type *PathError.
+func (f *File) Flush() error {
+	return f.checkValid("flush")
+}
+
+// ReadDir reads the contents of the directory and returns a slice of up
+// to n DirEntry values in the order of their names. If n <= 0, ReadDir
+// returns all remaining entries. If n > 0 and there are no entries left,
+// it returns io.EOF.
+func (f *File) ReadDir(n int) ([]fs.DirEntry, error) {
+	if err := f.checkValid("readdir"); err != nil {
+		return nil, err
+	}
+	if !f.node.IsDir {
+		return nil, &os.PathError{
+			Op:   "readdir",
+			Path: f.Name(),
+			Err:  syscall.ENOTDIR,
+		}
+	}
+	if n <= 0 {
+		entries := f.dir
+		f.dir = nil
+		return entries, nil
+	}
+	if len(f.dir) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(f.dir) {
+		n = len(f.dir)
+	}
+	entries := f.dir[:n:n]
+	f.dir = f.dir[n:]
+	return entries, nil
+}
+
+// Readdir is like ReadDir but returns FileInfo values, like
+// os.File.Readdir. It shares its position in the directory with ReadDir.
+func (f *File) Readdir(n int) ([]os.FileInfo, error) {
+	entries, err := f.ReadDir(n)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, len(entries))
+	for i, e := range entries {
+		if infos[i], err = e.Info(); err != nil {
+			return infos[:i], err
+		}
+	}
+	return infos, nil
+}
+
+// Readdirnames is like Readdir but only returns the names of the
+// entries, like os.File.Readdirnames.
+func (f *File) Readdirnames(n int) ([]string, error) {
+	entries, err := f.ReadDir(n)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+	return names, nil
+}
+
+// Close closes the file, rendering it unusable for I/O, and releases its
+// advisory lock. Close returns an error if it has already been called.
 func (f *File) Close() error {
+	if err := f.checkValid("close"); err != nil {
+		return err
+	}
+	f.Unlock()
+	f.closed = true
+	if f.node.fifo != nil && f.flag&(os.O_RDONLY|os.O_WRONLY|os.O_RDWR) != os.O_RDONLY {
+		f.node.Mu.Lock()
+		f.node.writers--
+		f.node.fifo.Broadcast()
+		f.node.Mu.Unlock()
+	}
+	f.node.closeHandle()
 	return nil
 }
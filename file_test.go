@@ -1,7 +1,16 @@
 package ramfs
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestReadWrite(t *testing.T) {
@@ -9,6 +18,7 @@ func TestReadWrite(t *testing.T) {
 	node := &Node{}
 	fd := &File{
 		node: node,
+		flag: os.O_RDWR,
 	}
 	n, err := fd.Write([]byte(want))
 	if err != nil {
@@ -19,6 +29,7 @@ func TestReadWrite(t *testing.T) {
 	}
 	fd2 := &File{
 		node: node,
+		flag: os.O_RDWR,
 	}
 	b := make([]byte, 1)
 	n, err = fd2.Read(b)
@@ -41,3 +52,812 @@ func TestReadWrite(t *testing.T) {
 		t.Fatalf("read() = %q, want %q", got, want)
 	}
 }
+
+func TestSeekEnd(t *testing.T) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write([]byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	off, err := fd.Seek(0, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off != 11 {
+		t.Fatalf("seek(0, end) = %d, want %d", off, 11)
+	}
+	off, err = fd.Seek(-5, io.SeekEnd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off != 6 {
+		t.Fatalf("seek(-5, end) = %d, want %d", off, 6)
+	}
+	b := make([]byte, 5)
+	if _, err := fd.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "world"; got != want {
+		t.Fatalf("read() = %q, want %q", got, want)
+	}
+	if _, err := fd.Seek(-12, io.SeekEnd); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("seek(-12, end) = %v, want %v", err, os.ErrInvalid)
+	}
+}
+
+func TestSeek(t *testing.T) {
+	tests := []struct {
+		offset  int64
+		whence  int
+		want    int64
+		wantErr error
+	}{
+		{offset: 3, whence: io.SeekStart, want: 3},
+		{offset: 2, whence: io.SeekCurrent, want: 7},
+		{offset: -1, whence: io.SeekEnd, want: 10},
+		{offset: 1 << 20, whence: io.SeekStart, want: 1 << 20},
+		{offset: -1, whence: io.SeekStart, want: 5, wantErr: os.ErrInvalid},
+		{offset: -6, whence: io.SeekCurrent, want: 5, wantErr: os.ErrInvalid},
+		{offset: 0, whence: 3, want: 5, wantErr: os.ErrInvalid},
+		{offset: 0, whence: -1, want: 5, wantErr: os.ErrInvalid},
+	}
+	for _, tc := range tests {
+		fd := &File{
+			node: &Node{},
+			flag: os.O_RDWR,
+		}
+		if _, err := fd.Write([]byte("hello world")); err != nil {
+			t.Fatal(err)
+		}
+		fd.offset = 5
+		got, err := fd.Seek(tc.offset, tc.whence)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("seek(%d, %d) = %v, want %v", tc.offset, tc.whence, err, tc.wantErr)
+		}
+		if err != nil {
+			var pe *os.PathError
+			if !errors.As(err, &pe) {
+				t.Errorf("seek(%d, %d) = %T, want *os.PathError", tc.offset, tc.whence, err)
+			}
+		}
+		if got != tc.want {
+			t.Errorf("seek(%d, %d) = %d, want %d", tc.offset, tc.whence, got, tc.want)
+		}
+	}
+}
+
+func TestReadAt(t *testing.T) {
+	want := "hello world"
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write([]byte(want)); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(off int) {
+			defer wg.Done()
+			b := make([]byte, 3)
+			n, err := fd.ReadAt(b, int64(off))
+			if err != nil {
+				t.Errorf("readat(%d) = %v", off, err)
+				return
+			}
+			if got := string(b[:n]); got != want[off:off+3] {
+				t.Errorf("readat(%d) = %q, want %q", off, got, want[off:off+3])
+			}
+		}(i)
+	}
+	wg.Wait()
+	if fd.offset != len(want) {
+		t.Fatalf("offset = %d, want %d", fd.offset, len(want))
+	}
+	b := make([]byte, 10)
+	n, err := fd.ReadAt(b, 6)
+	if err != io.EOF {
+		t.Fatalf("readat(6) = %v, want %v", err, io.EOF)
+	}
+	if got := string(b[:n]); got != "world" {
+		t.Fatalf("readat(6) = %q, want %q", got, "world")
+	}
+}
+
+// TestConcurrentNode hammers a single node through several Files to let
+// the race detector prove that no slice of the data outlives its lock.
+func TestConcurrentNode(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		fd, err := fs.OpenFile("foo", os.O_RDWR, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer fd.Close()
+			b := make([]byte, 16)
+			for j := 0; j < 200; j++ {
+				switch (i + j) % 8 {
+				case 0:
+					fd.Write([]byte("hello world"))
+				case 1:
+					fd.Read(b)
+				case 2:
+					fd.Truncate(int64(j % 32))
+				case 3:
+					fd.WriteAt([]byte("xyz"), int64(j%20))
+				case 4:
+					fd.ReadAt(b, int64(j%20))
+				case 5:
+					fd.Seek(0, io.SeekEnd)
+				case 6:
+					fd.ReadFrom(strings.NewReader("abc"))
+				case 7:
+					fd.Seek(0, io.SeekStart)
+					fd.WriteTo(io.Discard)
+				}
+			}
+		}(i)
+	}
+	for j := 0; j < 100; j++ {
+		fs.ReadFile("foo")
+		fs.Stat("foo")
+	}
+	wg.Wait()
+}
+
+func TestWriteAt(t *testing.T) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	n, err := fd.WriteAt([]byte("world"), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Fatalf("writeat() = %d, want %d", n, 5)
+	}
+	if _, err := fd.WriteAt([]byte("J"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if fd.offset != 5 {
+		t.Fatalf("offset = %d, want %d", fd.offset, 5)
+	}
+	want := "Jello\x00\x00\x00world"
+	if got := fd.node.Data.String(); got != want {
+		t.Fatalf("data = %q, want %q", got, want)
+	}
+}
+
+func TestWritePastEnd(t *testing.T) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Seek(100, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	n, err := fd.Write([]byte("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("write() = %d, want %d", n, 1)
+	}
+	if fd.offset != 101 {
+		t.Fatalf("offset = %d, want %d", fd.offset, 101)
+	}
+	d := fd.node.Data.Bytes()
+	if len(d) != 101 {
+		t.Fatalf("len = %d, want %d", len(d), 101)
+	}
+	if d[100] != 'x' {
+		t.Fatalf("data[100] = %q, want %q", d[100], 'x')
+	}
+	for i, b := range d[:100] {
+		if b != 0 {
+			t.Fatalf("data[%d] = %q, want 0", i, b)
+		}
+	}
+}
+
+func TestSeekPastEnd(t *testing.T) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write([]byte("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+	if off, err := fd.Seek(1000, io.SeekStart); off != 1000 || err != nil {
+		t.Fatalf("seek(1000, start) = %d, %v, want 1000, nil", off, err)
+	}
+	b := make([]byte, 4)
+	if n, err := fd.Read(b); n != 0 || err != io.EOF {
+		t.Fatalf("read() past end = %d, %v, want 0, %v", n, err, io.EOF)
+	}
+	if n, err := fd.ReadAt(b, 1000); n != 0 || err != io.EOF {
+		t.Fatalf("readat(1000) = %d, %v, want 0, %v", n, err, io.EOF)
+	}
+	if fd.node.Data.Len() != 10 {
+		t.Fatalf("len = %d after seek, want %d", fd.node.Data.Len(), 10)
+	}
+	if n, err := fd.Write(nil); n != 0 || err != nil {
+		t.Fatalf("write(nil) = %d, %v, want 0, nil", n, err)
+	}
+	if n, err := fd.ReadFrom(strings.NewReader("")); n != 0 || err != nil {
+		t.Fatalf("readfrom(\"\") = %d, %v, want 0, nil", n, err)
+	}
+	if n, err := fd.WriteAt(nil, 2000); n != 0 || err != nil {
+		t.Fatalf("writeat(nil, 2000) = %d, %v, want 0, nil", n, err)
+	}
+	if fd.node.Data.Len() != 10 {
+		t.Fatalf("len = %d after empty writes, want %d", fd.node.Data.Len(), 10)
+	}
+	if _, err := fd.Write([]byte("end")); err != nil {
+		t.Fatal(err)
+	}
+	if off, err := fd.Seek(0, io.SeekEnd); off != 1003 || err != nil {
+		t.Fatalf("seek(0, end) = %d, %v, want 1003, nil", off, err)
+	}
+	if off, err := fd.Seek(-1003, io.SeekCurrent); off != 0 || err != nil {
+		t.Fatalf("seek(-1003, current) = %d, %v, want 0, nil", off, err)
+	}
+	got, err := io.ReadAll(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0123456789" + strings.Repeat("\x00", 990) + "end"
+	if string(got) != want {
+		t.Fatalf("readall() = %q, want %q", got, want)
+	}
+}
+
+func BenchmarkParallelRead(b *testing.B) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write(make([]byte, 1<<16)); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(1 << 10)
+	b.RunParallel(func(pb *testing.PB) {
+		p := make([]byte, 1<<10)
+		off := int64(0)
+		for pb.Next() {
+			if _, err := fd.ReadAt(p, off); err != nil {
+				b.Fatal(err)
+			}
+			off = (off + 1<<10) % (1 << 16)
+		}
+	})
+}
+
+func TestReadEOF(t *testing.T) {
+	want := "hello world"
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write([]byte(want)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("read() at EOF = %v, want %v", err, io.EOF)
+	}
+	if _, err := fd.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, fd)
+	if err != nil {
+		t.Fatalf("copy() = %v", err)
+	}
+	if n != int64(len(want)) {
+		t.Fatalf("copy() = %d, want %d", n, len(want))
+	}
+	if got := buf.String(); got != want {
+		t.Fatalf("copy() = %q, want %q", got, want)
+	}
+}
+
+func TestReadShort(t *testing.T) {
+	want := "hello"
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write([]byte(want)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 64)
+	n, err := fd.Read(b)
+	if err != nil {
+		t.Fatalf("read() = %v, want nil", err)
+	}
+	if got := string(b[:n]); got != want {
+		t.Fatalf("read() = %q, want %q", got, want)
+	}
+	if n, err := fd.Read(b); n != 0 || err != io.EOF {
+		t.Fatalf("read() = %d, %v, want 0, %v", n, err, io.EOF)
+	}
+}
+
+func TestReadEmpty(t *testing.T) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	for _, off := range []int64{0, 3, 5, 10} {
+		if _, err := fd.Seek(off, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range [][]byte{nil, {}} {
+			if n, err := fd.Read(p); n != 0 || err != nil {
+				t.Fatalf("read(%v) at %d = %d, %v, want 0, nil", p, off, n, err)
+			}
+		}
+	}
+}
+
+func TestWriteString(t *testing.T) {
+	fd1 := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	fd2 := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	for _, s := range []string{"hello world", "", "!"} {
+		n1, err1 := fd1.Write([]byte(s))
+		n2, err2 := fd2.WriteString(s)
+		if n1 != n2 || err1 != err2 {
+			t.Fatalf("writestring(%q) = %d, %v, want %d, %v", s, n2, err2, n1, err1)
+		}
+		fd1.Seek(-3, io.SeekCurrent)
+		fd2.Seek(-3, io.SeekCurrent)
+	}
+	if got, want := fd2.node.Data.String(), fd1.node.Data.String(); got != want {
+		t.Fatalf("data = %q, want %q", got, want)
+	}
+	if _, err := fmt.Fprintf(fd2, "%d", 42); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWriteStringDifferential applies the same random operations to two
+// files, writing with Write to the first one and with a mix of Write and
+// WriteString to the second one.
+func TestWriteStringDifferential(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	fd1 := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	fd2 := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	for i := 0; i < 1000; i++ {
+		switch r.Intn(4) {
+		case 0, 1:
+			s := strings.Repeat(string(rune('a'+r.Intn(26))), r.Intn(16))
+			n1, err1 := fd1.Write([]byte(s))
+			var n2 int
+			var err2 error
+			if r.Intn(2) == 0 {
+				n2, err2 = fd2.WriteString(s)
+			} else {
+				n2, err2 = fd2.Write([]byte(s))
+			}
+			if n1 != n2 || err1 != err2 {
+				t.Fatalf("op %d: write(%q) = %d, %v, want %d, %v", i, s, n2, err2, n1, err1)
+			}
+		case 2:
+			off := int64(r.Intn(64))
+			fd1.Seek(off, io.SeekStart)
+			fd2.Seek(off, io.SeekStart)
+		case 3:
+			size := int64(r.Intn(48))
+			fd1.Truncate(size)
+			fd2.Truncate(size)
+		}
+		if got, want := fd2.node.Data.String(), fd1.node.Data.String(); got != want {
+			t.Fatalf("op %d: data = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	want := "hello world"
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write([]byte(want)); err != nil {
+		t.Fatal(err)
+	}
+	fd.Seek(6, io.SeekStart)
+	var buf bytes.Buffer
+	n, err := fd.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 || buf.String() != "world" {
+		t.Fatalf("writeto() = %d %q, want %d %q", n, buf.String(), 5, "world")
+	}
+	if fd.offset != len(want) {
+		t.Fatalf("offset = %d, want %d", fd.offset, len(want))
+	}
+}
+
+func TestWriteToSameFile(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := fs.Open("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	w, err := fs.OpenFile("foo", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(w, r)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("copy() of a file onto itself did not return")
+	}
+	if b, err := fs.ReadFile("foo"); err != nil || string(b) != "hellohello" {
+		t.Fatalf("readfile(foo) = %q, %v, want %q", b, err, "hellohello")
+	}
+}
+
+func benchmarkCopy(b *testing.B, wrap func(*File) io.Reader) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write(make([]byte, 1<<20)); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(1 << 20)
+	for i := 0; i < b.N; i++ {
+		fd.Seek(0, io.SeekStart)
+		if _, err := io.Copy(io.Discard, wrap(fd)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCopyRead(b *testing.B) {
+	benchmarkCopy(b, func(f *File) io.Reader { return struct{ io.Reader }{f} })
+}
+
+func BenchmarkCopyWriteTo(b *testing.B) {
+	benchmarkCopy(b, func(f *File) io.Reader { return f })
+}
+
+func benchmarkWrites(b *testing.B, grow bool) {
+	chunk := make([]byte, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fd := &File{
+			node: &Node{},
+			flag: os.O_RDWR,
+		}
+		if grow {
+			fd.Grow(1 << 20)
+		}
+		for j := 0; j < (1<<20)/len(chunk); j++ {
+			if _, err := fd.Write(chunk); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkWrites(b *testing.B) {
+	benchmarkWrites(b, false)
+}
+
+func BenchmarkWritesGrow(b *testing.B) {
+	benchmarkWrites(b, true)
+}
+
+func BenchmarkOverwrite(b *testing.B) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	data := make([]byte, 1<<20)
+	if _, err := fd.Write(data); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		if _, err := fd.WriteAt(data, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkConcurrentAppend(b *testing.B) {
+	node := &Node{}
+	record := make([]byte, 64)
+	b.SetBytes(int64(len(record)))
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		fd := &File{
+			node: node,
+			flag: os.O_WRONLY | os.O_APPEND,
+		}
+		for pb.Next() {
+			if _, err := fd.Write(record); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestGrow(t *testing.T) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	fd.Grow(100)
+	if got := fd.node.Data.Cap(); got < 100 {
+		t.Fatalf("cap() = %d, want at least %d", got, 100)
+	}
+	if st, _ := fd.Stat(); st.Size() != 0 {
+		t.Fatalf("size() = %d after grow, want %d", st.Size(), 0)
+	}
+}
+
+func TestReadFrom(t *testing.T) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write([]byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	fd.Seek(6, io.SeekStart)
+	src := bytes.Repeat([]byte("0123456789"), 1<<16)
+	n, err := io.Copy(fd, io.LimitReader(bytes.NewReader(src), int64(len(src))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(len(src)) {
+		t.Fatalf("copy() = %d, want %d", n, len(src))
+	}
+	if want := 6 + len(src); fd.offset != want {
+		t.Fatalf("offset = %d, want %d", fd.offset, want)
+	}
+	want := append([]byte("hello "), src...)
+	if got := fd.node.Data.Bytes(); !bytes.Equal(got, want) {
+		t.Fatalf("data mismatch: len %d, want %d", len(got), len(want))
+	}
+	fd.Seek(0, io.SeekStart)
+	if _, err := fd.ReadFrom(strings.NewReader("J")); err != nil {
+		t.Fatal(err)
+	}
+	if got := fd.node.Data.String()[:5]; got != "Jello" {
+		t.Fatalf("data = %q, want %q", got, "Jello")
+	}
+}
+
+func TestReadFromBlocking(t *testing.T) {
+	fs := New()
+	fd, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := fd.ReadFrom(pr)
+		done <- err
+	}()
+	if _, err := pw.Write([]byte("abc")); err != nil {
+		t.Fatal(err)
+	}
+	// An empty write only returns once ReadFrom is reading again, after
+	// it wrote abc.
+	if _, err := pw.Write(nil); err != nil {
+		t.Fatal(err)
+	}
+	// ReadFrom is now waiting for more data, which must not keep others
+	// from using the file or the filesystem.
+	stat := make(chan os.FileInfo, 1)
+	go func() {
+		st, _ := fs.Stat("foo")
+		stat <- st
+	}()
+	select {
+	case st := <-stat:
+		if st == nil || st.Size() != 3 {
+			t.Fatalf("stat(foo) = %v, want size 3", st)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("stat(foo) blocked by readfrom")
+	}
+	pw.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("readfrom() = %v", err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write([]byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Truncate(5); err != nil {
+		t.Fatalf("truncate(5) = %v", err)
+	}
+	if got := fd.node.Data.String(); got != "hello" {
+		t.Fatalf("data = %q, want %q", got, "hello")
+	}
+	if err := fd.Truncate(8); err != nil {
+		t.Fatalf("truncate(8) = %v", err)
+	}
+	if got := fd.node.Data.String(); got != "hello\x00\x00\x00" {
+		t.Fatalf("data = %q, want %q", got, "hello\x00\x00\x00")
+	}
+	if err := fd.Truncate(-1); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("truncate(-1) = %v, want %v", err, os.ErrInvalid)
+	}
+}
+
+func TestAppendOnly(t *testing.T) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR | os.O_APPEND,
+	}
+	if _, err := fd.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte(" world")); err != nil {
+		t.Fatalf("write() = %v, want nil", err)
+	}
+	if err := fd.Truncate(0); !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("truncate(0) = %v, want %v", err, os.ErrPermission)
+	}
+	if _, err := fd.WriteAt([]byte("j"), 0); !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("writeat(0) = %v, want %v", err, os.ErrPermission)
+	}
+	if got := fd.node.Data.String(); got != "hello world" {
+		t.Fatalf("data = %q, want %q", got, "hello world")
+	}
+}
+
+func TestModTime(t *testing.T) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	st, _ := fd.Stat()
+	mtime := st.ModTime()
+	if mtime.IsZero() {
+		t.Fatalf("mtime is zero after write")
+	}
+	for _, op := range []func() error{
+		func() error { _, err := fd.Write([]byte("x")); return err },
+		func() error { _, err := fd.WriteAt([]byte("x"), 0); return err },
+		func() error { return fd.Truncate(2) },
+	} {
+		time.Sleep(time.Millisecond)
+		if err := op(); err != nil {
+			t.Fatal(err)
+		}
+		st, _ := fd.Stat()
+		if !st.ModTime().After(mtime) {
+			t.Fatalf("mtime = %v, want after %v", st.ModTime(), mtime)
+		}
+		mtime = st.ModTime()
+	}
+}
+
+func TestClose(t *testing.T) {
+	fd := &File{
+		node: &Node{},
+		flag: os.O_RDWR,
+	}
+	if _, err := fd.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Sync(); err != nil {
+		t.Fatalf("sync() = %v, want nil", err)
+	}
+	if err := fd.Flush(); err != nil {
+		t.Fatalf("flush() = %v, want nil", err)
+	}
+	if err := fd.Close(); err != nil {
+		t.Fatalf("close() = %v, want nil", err)
+	}
+	b := make([]byte, 5)
+	for op, fn := range map[string]func() error{
+		"read": func() error {
+			_, err := fd.Read(b)
+			return err
+		},
+		"readat": func() error {
+			_, err := fd.ReadAt(b, 0)
+			return err
+		},
+		"write": func() error {
+			_, err := fd.Write(b)
+			return err
+		},
+		"writeat": func() error {
+			_, err := fd.WriteAt(b, 0)
+			return err
+		},
+		"seek": func() error {
+			_, err := fd.Seek(0, 0)
+			return err
+		},
+		"stat": func() error {
+			_, err := fd.Stat()
+			return err
+		},
+		"truncate": func() error {
+			return fd.Truncate(0)
+		},
+		"sync":  fd.Sync,
+		"flush": fd.Flush,
+		"close": fd.Close,
+	} {
+		err := fn()
+		if !errors.Is(err, os.ErrClosed) {
+			t.Fatalf("%s() = %v, want %v", op, err, os.ErrClosed)
+		}
+		var perr *os.PathError
+		if !errors.As(err, &perr) || perr.Op != op {
+			t.Fatalf("%s() = %#v, want *PathError for op %q", op, err, op)
+		}
+	}
+}
@@ -1,30 +1,325 @@
 package ramfs
 
 import (
+	"bytes"
+	"context"
 	"io"
-	"log"
+	iofs "io/fs"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
+// Clock provides the current time for timestamps in the filesystem.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
 // Filesystem is used to hold all information about the filesystem.
 type Filesystem struct {
 	mu    sync.Mutex
+	clock Clock
+	root  *Node
 	files map[string]*Node
+
+	// used is the total size of all file data, quota the limit for it.
+	used        atomic.Int64
+	quota       atomic.Int64
+	maxFileSize atomic.Int64
+	// inodes is the last inode number handed out.
+	inodes atomic.Uint64
+
+	watchMu  sync.Mutex
+	watchers map[*watcher]bool
+	observer Observer
+
+	// folded maps the lower case form of every name in files to the name
+	// for case insensitive filesystems. It is nil otherwise.
+	folded map[string]string
 }
 
+// modeBits are the bits of a FileMode that can be changed with Chmod:
+// the permission bits and the setuid, setgid and sticky bits. The
+// remaining bits describe the type of a file.
+const modeBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
 // New creates a new Filesystem
 func New() *Filesystem {
-	return &Filesystem{
+	fs := &Filesystem{
+		clock: realClock{},
 		files: make(map[string]*Node),
 	}
+	fs.root = fs.newNode(".", os.ModeDir|0755)
+	return fs
+}
+
+// SetClock sets the clock used for timestamps. It must be called before
+// the filesystem is used.
+func (fs *Filesystem) SetClock(c Clock) {
+	fs.clock = c
+}
+
+// SetQuota limits the total size of all file data to bytes. Writes that
+// would exceed the quota fail with syscall.ENOSPC. A quota of zero or less
+// removes the limit.
+func (fs *Filesystem) SetQuota(bytes int64) {
+	fs.quota.Store(bytes)
+}
+
+// SetMaxFileSize limits the size of every single file to bytes. Writes
+// that would grow a file beyond the limit fail with syscall.ENOSPC. A
+// limit of zero or less removes it.
+func (fs *Filesystem) SetMaxFileSize(bytes int64) {
+	fs.maxFileSize.Store(bytes)
+}
+
+// Usage returns the number of entries in the filesystem and the total size
+// of their data.
+func (fs *Filesystem) Usage() (files int, bytes int64) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	seen := make(map[*Node]bool, len(fs.files))
+	for _, f := range fs.files {
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		f.Mu.RLock()
+		bytes += int64(f.Data.Len())
+		f.Mu.RUnlock()
+	}
+	return len(fs.files), bytes
+}
+
+// reserve accounts for the file data changing by delta bytes. It reports
+// false, without changing the accounting, if that would exceed the quota.
+func (fs *Filesystem) reserve(delta int64) bool {
+	for {
+		used := fs.used.Load()
+		if q := fs.quota.Load(); delta > 0 && q > 0 && used+delta > q {
+			return false
+		}
+		if fs.used.CompareAndSwap(used, used+delta) {
+			return true
+		}
+	}
+}
+
+// cleanPath returns the shortest name equivalent to name, as computed by
+// path.Clean. It reports false if name is absolute or refers to a location
+// outside of the root, in which case name is returned unchanged.
+func cleanPath(name string) (string, bool) {
+	if path.IsAbs(name) {
+		return name, false
+	}
+	c := path.Clean(name)
+	if c == ".." || strings.HasPrefix(c, "../") {
+		return name, false
+	}
+	return c, true
+}
+
+// clean is like cleanPath but returns a *PathError for op if name is
+// invalid.
+func clean(op, name string) (string, error) {
+	c, ok := cleanPath(name)
+	if !ok {
+		return "", &os.PathError{
+			Op:   op,
+			Err:  os.ErrInvalid,
+			Path: name,
+		}
+	}
+	return c, nil
+}
+
+// newNode returns a Node belonging to fs with its timestamps set to now.
+func (fs *Filesystem) newNode(name string, mode os.FileMode) *Node {
+	now := fs.clock.Now()
+	n := &Node{
+		Name:       name,
+		Mode:       mode,
+		IsDir:      mode.IsDir(),
+		ModTime:    now,
+		AccessTime: now,
+		ino:        fs.inodes.Add(1),
+		fs:         fs,
+	}
+	n.nlink.Store(1)
+	return n
+}
+
+// node returns the Node stored under name. Directories that are only
+// implied by the names of their descendants are returned as fresh
+// directory Nodes. The caller must hold fs.mu.
+func (fs *Filesystem) node(name string) (*Node, bool) {
+	if name == "." {
+		return fs.root, true
+	}
+	if f, ok := fs.files[name]; ok {
+		return f, true
+	}
+	prefix := name + "/"
+	for k := range fs.files {
+		if strings.HasPrefix(k, prefix) {
+			return &Node{
+				Name:  name,
+				Mode:  os.ModeDir | 0755,
+				IsDir: true,
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// readDir returns the entries of the directory name sorted by filename.
+// The caller must hold fs.mu.
+func (fs *Filesystem) readDir(name string) []iofs.DirEntry {
+	prefix := name + "/"
+	if name == "." {
+		prefix = ""
+	}
+	seen := make(map[string]bool)
+	var entries []iofs.DirEntry
+	for k := range fs.files {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		child := k[len(prefix):]
+		if i := strings.IndexByte(child, '/'); i >= 0 {
+			child = child[:i]
+		}
+		if seen[child] {
+			continue
+		}
+		seen[child] = true
+		f, _ := fs.node(prefix + child)
+		entries = append(entries, &DirEntry{node: f, name: prefix + child})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries
+}
+
+// ReadDir reads the named directory and returns a list of directory
+// entries sorted by filename. Nested paths are split on "/"; a directory
+// exists as long as it is created explicitly or contains any file.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) ReadDir(name string) ([]iofs.DirEntry, error) {
+	name, err := clean("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	resolved, err := fs.resolve(name)
+	if err != nil {
+		return nil, &os.PathError{
+			Op:   "readdir",
+			Err:  err,
+			Path: name,
+		}
+	}
+	f, ok := fs.node(resolved)
+	if !ok {
+		return nil, &os.PathError{
+			Op:   "readdir",
+			Err:  os.ErrNotExist,
+			Path: name,
+		}
+	}
+	if !f.IsDir {
+		return nil, &os.PathError{
+			Op:   "readdir",
+			Err:  syscall.ENOTDIR,
+			Path: name,
+		}
+	}
+	return fs.readDir(resolved), nil
+}
+
+// WalkDir walks the file tree rooted at root, calling fn for each file or
+// directory in the tree, including root, in lexical order. See
+// io/fs.WalkDir for the handling of fs.SkipDir and errors.
+func (fs *Filesystem) WalkDir(root string, fn iofs.WalkDirFunc) error {
+	return iofs.WalkDir(fs, root, fn)
+}
+
+// Glob returns the sorted names of all files matching pattern. The syntax
+// of patterns is the same as in path.Match; in particular "*" does not
+// match "/", so "**" behaves like "*" and only matches within a single
+// path element. On case insensitive filesystems, matching ignores case.
+// The only possible returned error is path.ErrBadPattern.
+func (fs *Filesystem) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	var matches []string
+	for k := range fs.files {
+		key := k
+		if fs.folded != nil {
+			pattern, key = strings.ToLower(pattern), strings.ToLower(k)
+		}
+		if ok, _ := path.Match(pattern, key); ok {
+			matches = append(matches, k)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// Range calls fn for every file in the filesystem, in lexical order of
+// their names, until fn returns false. Range works on a snapshot of the
+// names taken when it is called, so fn may modify the filesystem; files
+// created afterwards are not visited, while files removed afterwards are
+// still reported with their last state.
+func (fs *Filesystem) Range(fn func(name string, info os.FileInfo) bool) {
+	fs.mu.Lock()
+	names := make([]string, 0, len(fs.files))
+	nodes := make(map[string]*Node, len(fs.files))
+	for k, f := range fs.files {
+		names = append(names, k)
+		nodes[k] = f
+	}
+	fs.mu.Unlock()
+	sort.Strings(names)
+	for _, name := range names {
+		if !fn(name, nodes[name].stat(name)) {
+			return
+		}
+	}
 }
 
 // Open opens the named file for reading. If successful, methods on
 // the returned file can be used for reading; the associated file
-// descriptor has mode O_RDONLY.
+// descriptor has mode O_RDONLY. The returned file is a *File, which
+// makes Filesystem an implementation of io/fs.FS. Unlike the other
+// methods, Open does not clean name but rejects any name that does not
+// satisfy io/fs.ValidPath, as required by io/fs.FS.
 // If there is an error, it will be of type *PathError.
-func (fs *Filesystem) Open(name string) (*File, error) {
+func (fs *Filesystem) Open(name string) (iofs.File, error) {
+	if !iofs.ValidPath(name) {
+		return nil, &os.PathError{
+			Op:   "open",
+			Err:  os.ErrInvalid,
+			Path: name,
+		}
+	}
 	return fs.OpenFile(name, os.O_RDONLY, 0)
 }
 
@@ -34,9 +329,35 @@ func (fs *Filesystem) Open(name string) (*File, error) {
 // methods on the returned File can be used for I/O.
 // If there is an error, it will be of type *PathError.
 func (fs *Filesystem) OpenFile(name string, flag int, perm os.FileMode) (*File, error) {
+	name, err := clean("open", name)
+	if err != nil {
+		return nil, err
+	}
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
-	f, ok := fs.files[name]
+	resolved, err := fs.resolve(name)
+	if err != nil {
+		return nil, &os.PathError{
+			Op:   "open",
+			Err:  err,
+			Path: name,
+		}
+	}
+	return fs.openFile(name, resolved, flag, perm)
+}
+
+// openFile opens the file name, which resolves to resolved, like OpenFile.
+// The caller must hold fs.mu.
+func (fs *Filesystem) openFile(name, resolved string, flag int, perm os.FileMode) (*File, error) {
+	if flag&os.O_TRUNC != 0 && flag&(os.O_RDONLY|os.O_WRONLY|os.O_RDWR) == os.O_RDONLY {
+		// Truncating requires write access.
+		return nil, &os.PathError{
+			Op:   "open",
+			Err:  os.ErrInvalid,
+			Path: name,
+		}
+	}
+	f, ok := fs.node(resolved)
 	if !ok {
 		if flag&os.O_CREATE == 0 {
 			return nil, &os.PathError{
@@ -45,31 +366,204 @@ func (fs *Filesystem) OpenFile(name string, flag int, perm os.FileMode) (*File,
 				Path: name,
 			}
 		}
-		f = &Node{
-			Name: name,
-			Mode: perm,
+		parent, ok := fs.node(path.Dir(resolved))
+		if !ok {
+			return nil, &os.PathError{
+				Op:   "open",
+				Err:  os.ErrNotExist,
+				Path: name,
+			}
+		}
+		if !parent.IsDir {
+			return nil, &os.PathError{
+				Op:   "open",
+				Err:  syscall.ENOTDIR,
+				Path: name,
+			}
 		}
-		fs.files[name] = f
-	}
-	if (f.Mode.Perm() & perm.Perm()) != perm.Perm() {
-		log.Printf("%x %x", f.Mode.Perm(), perm.Perm())
-		// TODO is this check correct?
+		f = fs.newNode(resolved, perm)
+		fs.put(resolved, f)
+		fs.emit(resolved, Create)
+	} else if flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL {
+		return nil, &os.PathError{
+			Op:   "open",
+			Err:  os.ErrExist,
+			Path: name,
+		}
+	} else if f.IsDir && (flag&(os.O_RDONLY|os.O_WRONLY|os.O_RDWR) != os.O_RDONLY || flag&os.O_TRUNC != 0) {
+		return nil, &os.PathError{
+			Op:   "open",
+			Err:  syscall.EISDIR,
+			Path: name,
+		}
+	} else if !f.canAccess(flag) {
 		return nil, &os.PathError{
 			Op:   "open",
 			Err:  os.ErrPermission,
 			Path: name,
 		}
 	}
+	if flag&os.O_TRUNC != 0 {
+		// Truncate the node directly: File.Truncate refuses O_APPEND.
+		f.Mu.Lock()
+		err := f.truncate(0)
+		f.Mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		f.emit(name, Write)
+	}
+	if f.fifo != nil && flag&(os.O_RDONLY|os.O_WRONLY|os.O_RDWR) != os.O_RDONLY {
+		f.Mu.Lock()
+		f.writers++
+		f.Mu.Unlock()
+	}
+	f.handles++
 	file := &File{
 		node: f,
+		name: name,
+		flag: flag,
 	}
-	if flag&os.O_TRUNC != 0 {
-		file.Truncate(0)
+	if f.IsDir {
+		file.dir = fs.readDir(resolved)
+	}
+	if fs.observer != nil {
+		fs.observer.OnOpen(name)
 	}
 
 	return file, nil
 }
 
+// OpenContext is like OpenFile but fails with the error of ctx if ctx is
+// done before the file is opened. The returned File keeps ctx: Lock stops
+// waiting for a contended lock once ctx is done. Read and Write ignore
+// ctx; apart from reads from an empty FIFO, they never block.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) OpenContext(ctx context.Context, name string, flag int, perm os.FileMode) (*File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &os.PathError{
+			Op:   "open",
+			Err:  err,
+			Path: name,
+		}
+	}
+	f, err := fs.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	f.ctx = ctx
+	return f, nil
+}
+
+// canAccess reports whether the owner permission bits of the node allow
+// the access mode requested by flag.
+func (n *Node) canAccess(flag int) bool {
+	n.Mu.RLock()
+	defer n.Mu.RUnlock()
+	switch flag & (os.O_RDONLY | os.O_WRONLY | os.O_RDWR) {
+	case os.O_RDONLY:
+		return n.Mode&0400 != 0
+	case os.O_WRONLY:
+		return n.Mode&0200 != 0
+	default:
+		return n.Mode&0600 == 0600
+	}
+}
+
+// Mkdir creates a new directory with the specified name and permission
+// bits (before umask).
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Mkdir(name string, perm os.FileMode) error {
+	name, err := clean("mkdir", name)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	resolved, err := fs.resolveParent(name)
+	if err != nil {
+		return &os.PathError{
+			Op:   "mkdir",
+			Err:  err,
+			Path: name,
+		}
+	}
+	if _, ok := fs.node(resolved); ok {
+		return &os.PathError{
+			Op:   "mkdir",
+			Err:  os.ErrExist,
+			Path: name,
+		}
+	}
+	parent, ok := fs.node(path.Dir(resolved))
+	if !ok {
+		return &os.PathError{
+			Op:   "mkdir",
+			Err:  os.ErrNotExist,
+			Path: name,
+		}
+	}
+	if !parent.IsDir {
+		return &os.PathError{
+			Op:   "mkdir",
+			Err:  syscall.ENOTDIR,
+			Path: name,
+		}
+	}
+	fs.put(resolved, fs.newNode(resolved, os.ModeDir|perm&modeBits))
+	fs.emit(resolved, Create)
+	return nil
+}
+
+// MkdirAll creates a directory named name, along with any necessary
+// parents, and returns nil, or else returns an error. The permission bits
+// perm (before umask) are used for all directories that MkdirAll creates.
+// If name is already a directory, MkdirAll does nothing and returns nil.
+func (fs *Filesystem) MkdirAll(name string, perm os.FileMode) error {
+	name, err := clean("mkdir", name)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	resolved, err := fs.resolve(name)
+	if err != nil {
+		return &os.PathError{
+			Op:   "mkdir",
+			Err:  err,
+			Path: name,
+		}
+	}
+	if f, ok := fs.node(resolved); ok {
+		if f.IsDir {
+			return nil
+		}
+		return &os.PathError{
+			Op:   "mkdir",
+			Err:  syscall.ENOTDIR,
+			Path: name,
+		}
+	}
+	elems := strings.Split(resolved, "/")
+	for i := range elems {
+		dir := strings.Join(elems[:i+1], "/")
+		f, ok := fs.node(dir)
+		if !ok {
+			fs.put(dir, fs.newNode(dir, os.ModeDir|perm&modeBits))
+			fs.emit(dir, Create)
+			continue
+		}
+		if !f.IsDir {
+			return &os.PathError{
+				Op:   "mkdir",
+				Err:  syscall.ENOTDIR,
+				Path: dir,
+			}
+		}
+	}
+	return nil
+}
+
 // Create creates the named file with mode 0666 (before umask), truncating
 // it if it already exists. If successful, methods on the returned
 // File can be used for I/O; the associated file descriptor has mode
@@ -79,39 +573,908 @@ func (fs *Filesystem) Create(name string) (*File, error) {
 	return fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
 }
 
-// Chmod changes the mode of the named file to mode.
-func (fs *Filesystem) Chmod(name string, mode os.FileMode) error {
+// CreateSize is like Create but reserves capacity for size bytes, so that
+// writing up to size bytes to the returned File does not reallocate its
+// data. It does not change the size of the file.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) CreateSize(name string, size int) (*File, error) {
+	f, err := fs.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	f.Grow(size)
+	return f, nil
+}
+
+// Stat returns a FileInfo describing the named file.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Stat(name string) (os.FileInfo, error) {
+	name, err := clean("stat", name)
+	if err != nil {
+		return nil, err
+	}
 	fs.mu.Lock()
 	defer fs.mu.Unlock()
-	f, ok := fs.files[name]
+	resolved, err := fs.resolve(name)
+	if err != nil {
+		return nil, &os.PathError{
+			Op:   "stat",
+			Err:  err,
+			Path: name,
+		}
+	}
+	return fs.stat(name, resolved)
+}
+
+// stat describes the file name, which resolves to resolved, like Stat.
+// The caller must hold fs.mu.
+func (fs *Filesystem) stat(name, resolved string) (os.FileInfo, error) {
+	f, ok := fs.node(resolved)
 	if !ok {
-		return &os.PathError{
-			Op:   "chmod",
-			Err:  os.ErrExist,
+		return nil, &os.PathError{
+			Op:   "stat",
+			Err:  os.ErrNotExist,
 			Path: name,
 		}
 	}
-	f.Mode = mode
-	return nil
+	// Report the file under the name it was asked for, as stored: a
+	// symbolic link in the last element is not followed for the name.
+	if stored, err := fs.resolveParent(name); err == nil {
+		name = stored
+	}
+	return f.stat(name), nil
 }
 
-// MapFile maps a file from the host system into the guest system.
-func (fs *Filesystem) MapFile(hostname, guestname string) error {
-	f, err := os.Open(hostname)
+// Exists reports whether the named file or directory exists. Symbolic
+// links are not followed.
+func (fs *Filesystem) Exists(name string) bool {
+	name, ok := cleanPath(name)
+	if !ok {
+		return false
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	resolved, err := fs.resolveParent(name)
 	if err != nil {
-		return err
+		return false
 	}
-	defer f.Close()
-	stat, err := f.Stat()
+	_, ok = fs.node(resolved)
+	return ok
+}
+
+// Truncate changes the size of the named file. If the file grows, the
+// new bytes are zero.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Truncate(name string, size int64) error {
+	name, err := clean("truncate", name)
 	if err != nil {
 		return err
 	}
-	fg, err := fs.Create(guestname)
+	fs.mu.Lock()
+	resolved, err := fs.resolve(name)
 	if err != nil {
-		return err
+		fs.mu.Unlock()
+		return &os.PathError{
+			Op:   "truncate",
+			Err:  err,
+			Path: name,
+		}
 	}
-	if _, err := io.Copy(fg, f); err != nil {
+	f, ok := fs.node(resolved)
+	fs.mu.Unlock()
+	if !ok {
+		return &os.PathError{
+			Op:   "truncate",
+			Err:  os.ErrNotExist,
+			Path: name,
+		}
+	}
+	if f.IsDir {
+		return &os.PathError{
+			Op:   "truncate",
+			Err:  syscall.EISDIR,
+			Path: name,
+		}
+	}
+	if !f.canAccess(os.O_WRONLY) {
+		return &os.PathError{
+			Op:   "truncate",
+			Err:  os.ErrPermission,
+			Path: name,
+		}
+	}
+	file := &File{
+		node: f,
+		flag: os.O_WRONLY,
+	}
+	return file.Truncate(size)
+}
+
+// ReadFile reads the named file and returns its contents in a freshly
+// allocated slice. Like Open, and as required by io/fs.ReadFileFS, it only
+// accepts names that satisfy io/fs.ValidPath.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) ReadFile(name string) ([]byte, error) {
+	if !iofs.ValidPath(name) {
+		return nil, &os.PathError{
+			Op:   "open",
+			Err:  os.ErrInvalid,
+			Path: name,
+		}
+	}
+	f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if f.node.IsDir {
+		return nil, &os.PathError{
+			Op:   "read",
+			Err:  syscall.EISDIR,
+			Path: name,
+		}
+	}
+	// Copy the data in a single allocation; the caller owns the result.
+	f.node.Mu.RLock()
+	defer f.node.Mu.RUnlock()
+	f.node.touch()
+	d := f.node.Data.Bytes()
+	b := make([]byte, len(d))
+	copy(b, d)
+	f.observeRead(len(b))
+	return b, nil
+}
+
+// Bytes returns the contents of the named file without copying them.
+// The returned slice aliases the data of the file: it must not be
+// modified, and it is only valid until the next write to or truncation of
+// the file, which may or may not be reflected in it. Use ReadFile to get a
+// copy that is safe to keep.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Bytes(name string) ([]byte, error) {
+	f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if f.node.IsDir {
+		return nil, &os.PathError{
+			Op:   "read",
+			Err:  syscall.EISDIR,
+			Path: name,
+		}
+	}
+	f.node.Mu.RLock()
+	defer f.node.Mu.RUnlock()
+	f.node.touch()
+	f.observeRead(f.node.Data.Len())
+	return f.node.Data.Bytes(), nil
+}
+
+// Reader returns a reader for the contents of the named file. The reader
+// works on a snapshot of the file taken when Reader is called: later
+// writes to the file are not reflected in it. The snapshot shares the data
+// of the file until the file is modified next, which then copies the data.
+// Closing the reader does nothing. For a FIFO, Reader returns the FIFO
+// opened for reading instead, which has to be closed.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Reader(name string) (io.ReadCloser, error) {
+	f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	if f.node.fifo != nil {
+		return f, nil
+	}
+	defer f.Close()
+	if f.node.IsDir {
+		return nil, &os.PathError{
+			Op:   "read",
+			Err:  syscall.EISDIR,
+			Path: name,
+		}
+	}
+	f.node.Mu.Lock()
+	defer f.node.Mu.Unlock()
+	f.node.touch()
+	f.node.cow = true
+	d := f.node.Data.Bytes()
+	f.observeRead(len(d))
+	return io.NopCloser(bytes.NewReader(d[:len(d):len(d)])), nil
+}
+
+// WriteFile writes data to the named file, creating it if necessary and
+// truncating it otherwise. The file's permission bits are set to perm.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) WriteFile(name string, data []byte, perm os.FileMode) error {
+	f, err := fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	f.node.Mu.Lock()
+	f.node.Mode = f.node.Mode&^modeBits | perm&modeBits
+	f.node.Mu.Unlock()
+	return nil
+}
+
+// Chmod changes the mode of the named file to mode. Only the permission
+// bits and os.ModeSetuid, os.ModeSetgid and os.ModeSticky of mode are
+// used. If the file is a symbolic link, it changes the mode of the link's
+// target.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Chmod(name string, mode os.FileMode) error {
+	return fs.chmod("chmod", name, mode, fs.resolve)
+}
+
+// chmod changes the mode of the file that resolve maps name to.
+func (fs *Filesystem) chmod(op, name string, mode os.FileMode, resolve func(string) (string, error)) error {
+	name, err := clean(op, name)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	resolved, err := resolve(name)
+	if err != nil {
+		return &os.PathError{
+			Op:   op,
+			Err:  err,
+			Path: name,
+		}
+	}
+	f, ok := fs.files[resolved]
+	if !ok {
+		return &os.PathError{
+			Op:   op,
+			Err:  os.ErrNotExist,
+			Path: name,
+		}
+	}
+	f.Mu.Lock()
+	f.Mode = f.Mode&^modeBits | mode&modeBits
+	f.Mu.Unlock()
+	fs.emit(resolved, Chmod)
+	return nil
+}
+
+// RemoveAll removes name and any children it contains. It removes
+// everything it can but returns the first error it encounters. If name
+// does not exist, RemoveAll returns nil. Symbolic links are removed, not
+// followed.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) RemoveAll(name string) error {
+	name, err := clean("removeall", name)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return &os.PathError{
+			Op:   "removeall",
+			Err:  os.ErrInvalid,
+			Path: name,
+		}
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	resolved, err := fs.resolveParent(name)
+	if err != nil {
+		return &os.PathError{
+			Op:   "removeall",
+			Err:  err,
+			Path: name,
+		}
+	}
+	var removed []string
+	prefix := resolved + "/"
+	for k, f := range fs.files {
+		if k == resolved || strings.HasPrefix(k, prefix) {
+			fs.del(k)
+			f.unlink()
+			removed = append(removed, k)
+		}
+	}
+	// Report children before their parents.
+	sort.Sort(sort.Reverse(sort.StringSlice(removed)))
+	for _, k := range removed {
+		fs.emit(k, Remove)
+		if fs.observer != nil {
+			fs.observer.OnRemove(k)
+		}
+	}
+	return nil
+}
+
+// Reset removes every file and directory, leaving an empty filesystem
+// that keeps its clock, quota, watchers and observer. Removal is reported
+// for every name, children before their parents. Data of files that are
+// still open counts against the quota until they are closed.
+func (fs *Filesystem) Reset() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	removed := make([]string, 0, len(fs.files))
+	for k, f := range fs.files {
+		f.unlink()
+		removed = append(removed, k)
+	}
+	fs.files = make(map[string]*Node)
+	if fs.folded != nil {
+		fs.folded = make(map[string]string)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(removed)))
+	for _, k := range removed {
+		fs.emit(k, Remove)
+		if fs.observer != nil {
+			fs.observer.OnRemove(k)
+		}
+	}
+}
+
+// Chown changes the numeric uid and gid of the named file. A uid or gid
+// of -1 means to not change that value.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Chown(name string, uid, gid int) error {
+	return fs.chown("chown", name, uid, gid, fs.resolve)
+}
+
+// lchown is like Chown but changes the owner of a symbolic link itself.
+func (fs *Filesystem) lchown(name string, uid, gid int) error {
+	return fs.chown("lchown", name, uid, gid, fs.resolveParent)
+}
+
+// chown changes the owner of the file that resolve maps name to.
+func (fs *Filesystem) chown(op, name string, uid, gid int, resolve func(string) (string, error)) error {
+	name, err := clean(op, name)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	resolved, err := resolve(name)
+	if err != nil {
+		return &os.PathError{
+			Op:   op,
+			Err:  err,
+			Path: name,
+		}
+	}
+	f, ok := fs.files[resolved]
+	if !ok {
+		return &os.PathError{
+			Op:   op,
+			Err:  os.ErrNotExist,
+			Path: name,
+		}
+	}
+	f.Mu.Lock()
+	defer f.Mu.Unlock()
+	if uid != -1 {
+		f.Uid = uid
+	}
+	if gid != -1 {
+		f.Gid = gid
+	}
+	fs.emit(resolved, Chmod)
+	return nil
+}
+
+// Remove removes the named file or (empty) directory.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Remove(name string) error {
+	name, err := clean("remove", name)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	resolved, err := fs.resolveParent(name)
+	if err != nil {
+		return &os.PathError{
+			Op:   "remove",
+			Err:  err,
+			Path: name,
+		}
+	}
+	f, ok := fs.files[resolved]
+	if !ok {
+		return &os.PathError{
+			Op:   "remove",
+			Err:  os.ErrNotExist,
+			Path: name,
+		}
+	}
+	if f.IsDir {
+		prefix := resolved + "/"
+		for k := range fs.files {
+			if strings.HasPrefix(k, prefix) {
+				return &os.PathError{
+					Op:   "remove",
+					Err:  syscall.ENOTEMPTY,
+					Path: name,
+				}
+			}
+		}
+	}
+	fs.del(resolved)
+	f.unlink()
+	fs.emit(resolved, Remove)
+	if fs.observer != nil {
+		fs.observer.OnRemove(resolved)
+	}
+	return nil
+}
+
+// Rename renames (moves) oldpath to newpath, along with the contents of
+// a directory. The parent directory of newpath must exist. If newpath
+// already exists and is not a directory, Rename replaces it. A directory
+// can only replace an empty directory, and a file cannot replace a
+// directory.
+// If there is an error, it will be of type *LinkError.
+func (fs *Filesystem) Rename(oldpath, newpath string) error {
+	oldclean, ok := cleanPath(oldpath)
+	newclean, newok := cleanPath(newpath)
+	if !ok || !newok {
+		return &os.LinkError{
+			Op:  "rename",
+			Old: oldpath,
+			New: newpath,
+			Err: os.ErrInvalid,
+		}
+	}
+	oldpath, newpath = oldclean, newclean
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	oldresolved, err := fs.resolveParent(oldpath)
+	if err == nil {
+		newpath, err = fs.resolveParent(newpath)
+	}
+	if err != nil {
+		return &os.LinkError{
+			Op:  "rename",
+			Old: oldpath,
+			New: newpath,
+			Err: err,
+		}
+	}
+	oldpath = oldresolved
+	f, ok := fs.node(oldpath)
+	if !ok || oldpath == "." {
+		return &os.LinkError{
+			Op:  "rename",
+			Old: oldpath,
+			New: newpath,
+			Err: os.ErrNotExist,
+		}
+	}
+	if f.IsDir && strings.HasPrefix(newpath+"/", oldpath+"/") {
+		if newpath == oldpath {
+			return nil
+		}
+		return &os.LinkError{
+			Op:  "rename",
+			Old: oldpath,
+			New: newpath,
+			Err: os.ErrInvalid,
+		}
+	}
+	if parent, ok := fs.node(path.Dir(newpath)); !ok || !parent.IsDir {
+		err := os.ErrNotExist
+		if ok {
+			err = syscall.ENOTDIR
+		}
+		return &os.LinkError{
+			Op:  "rename",
+			Old: oldpath,
+			New: newpath,
+			Err: err,
+		}
+	}
+	if g, ok := fs.node(newpath); ok {
+		var err error
+		switch {
+		case g.IsDir && !f.IsDir:
+			err = syscall.EISDIR
+		case g.IsDir && len(fs.readDir(newpath)) > 0:
+			err = syscall.ENOTEMPTY
+		case !g.IsDir && f.IsDir:
+			err = syscall.ENOTDIR
+		}
+		if err != nil {
+			return &os.LinkError{
+				Op:  "rename",
+				Old: oldpath,
+				New: newpath,
+				Err: err,
+			}
+		}
+		if g == f {
+			// Both names are links to the same file.
+			return nil
+		}
+		if _, ok := fs.files[newpath]; ok {
+			fs.del(newpath)
+			g.unlink()
+		}
+	}
+	fs.move(oldpath, newpath)
+	fs.emit(oldpath, Rename)
+	fs.emit(newpath, Create)
+	return nil
+}
+
+// Move moves src to dst along with the contents of a directory. Like
+// Rename, Move requires the parent directory of dst to exist, but unlike
+// Rename it never replaces a directory. If dst already exists and is not
+// a directory, Move replaces it.
+// The move is atomic: no other operation observes a partial move.
+// If there is an error, it will be of type *LinkError.
+func (fs *Filesystem) Move(src, dst string) error {
+	srcclean, ok := cleanPath(src)
+	dstclean, dstok := cleanPath(dst)
+	if !ok || !dstok {
+		return &os.LinkError{
+			Op:  "move",
+			Old: src,
+			New: dst,
+			Err: os.ErrInvalid,
+		}
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	srcclean, err := fs.resolveParent(srcclean)
+	if err == nil {
+		dstclean, err = fs.resolveParent(dstclean)
+	}
+	if err != nil {
+		return &os.LinkError{
+			Op:  "move",
+			Old: src,
+			New: dst,
+			Err: err,
+		}
+	}
+	if srcclean == "." || strings.HasPrefix(dstclean+"/", srcclean+"/") {
+		return &os.LinkError{
+			Op:  "move",
+			Old: src,
+			New: dst,
+			Err: os.ErrInvalid,
+		}
+	}
+	f, ok := fs.node(srcclean)
+	if !ok {
+		return &os.LinkError{
+			Op:  "move",
+			Old: src,
+			New: dst,
+			Err: os.ErrNotExist,
+		}
+	}
+	parent, ok := fs.node(path.Dir(dstclean))
+	if !ok {
+		return &os.LinkError{
+			Op:  "move",
+			Old: src,
+			New: dst,
+			Err: os.ErrNotExist,
+		}
+	}
+	if !parent.IsDir {
+		return &os.LinkError{
+			Op:  "move",
+			Old: src,
+			New: dst,
+			Err: syscall.ENOTDIR,
+		}
+	}
+	if g, ok := fs.node(dstclean); ok {
+		if g.IsDir {
+			return &os.LinkError{
+				Op:  "move",
+				Old: src,
+				New: dst,
+				Err: syscall.EISDIR,
+			}
+		}
+		if f.IsDir {
+			return &os.LinkError{
+				Op:  "move",
+				Old: src,
+				New: dst,
+				Err: syscall.ENOTDIR,
+			}
+		}
+		if g == f {
+			return nil
+		}
+		fs.del(dstclean)
+		g.unlink()
+	}
+	fs.move(srcclean, dstclean)
+	fs.emit(srcclean, Rename)
+	fs.emit(dstclean, Create)
+	return nil
+}
+
+// move re-keys src and everything below it to dst. The caller must hold
+// fs.mu.
+func (fs *Filesystem) move(src, dst string) {
+	moved := make(map[string]*Node)
+	prefix := src + "/"
+	for k, n := range fs.files {
+		if k == src || strings.HasPrefix(k, prefix) {
+			moved[dst+k[len(src):]] = n
+			fs.del(k)
+		}
+	}
+	for name, n := range moved {
+		fs.put(name, n)
+		n.Mu.Lock()
+		n.Name = name
+		n.Mu.Unlock()
+	}
+}
+
+// Copy copies the data and mode of the file src to dst, creating dst if
+// necessary and truncating it otherwise. Unlike Link, the copy does not
+// share its data with src. Copying a file onto itself fails with
+// ErrInvalid.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Copy(src, dst string) error {
+	in, err := fs.OpenFile(src, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if in.node.IsDir {
+		return &os.PathError{
+			Op:   "copy",
+			Err:  syscall.EISDIR,
+			Path: src,
+		}
+	}
+	in.node.Mu.RLock()
+	mode := in.node.Mode
+	in.node.Mu.RUnlock()
+	// dst is only truncated once it is known not to be src, which it may
+	// be under another name or through a hard link.
+	out, err := fs.OpenFile(dst, os.O_WRONLY|os.O_CREATE, mode&modeBits)
+	if err != nil {
+		return err
+	}
+	if out.node == in.node {
+		out.Close()
+		return &os.PathError{
+			Op:   "copy",
+			Err:  os.ErrInvalid,
+			Path: dst,
+		}
+	}
+	if err := out.Truncate(0); err != nil {
+		out.Close()
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	out.node.Mu.Lock()
+	out.node.Mode = mode
+	out.node.Mu.Unlock()
+	return out.Close()
+}
+
+// Chtimes changes the access and modification times of the named file.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Chtimes(name string, atime, mtime time.Time) error {
+	return fs.chtimes("chtimes", name, atime, mtime, fs.resolve)
+}
+
+// lchtimes is like Chtimes but changes the times of a symbolic link
+// itself.
+func (fs *Filesystem) lchtimes(name string, atime, mtime time.Time) error {
+	return fs.chtimes("lchtimes", name, atime, mtime, fs.resolveParent)
+}
+
+// chtimes changes the times of the file that resolve maps name to.
+func (fs *Filesystem) chtimes(op, name string, atime, mtime time.Time, resolve func(string) (string, error)) error {
+	name, err := clean(op, name)
+	if err != nil {
+		return err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	resolved, err := resolve(name)
+	if err != nil {
+		return &os.PathError{
+			Op:   op,
+			Err:  err,
+			Path: name,
+		}
+	}
+	f, ok := fs.files[resolved]
+	if !ok {
+		return &os.PathError{
+			Op:   op,
+			Err:  os.ErrNotExist,
+			Path: name,
+		}
+	}
+	f.Mu.Lock()
+	f.ModTime = mtime
+	f.Mu.Unlock()
+	f.atimeMu.Lock()
+	f.AccessTime = atime
+	f.atimeMu.Unlock()
+	fs.emit(resolved, Chmod)
+	return nil
+}
+
+// Clone returns an independent deep copy of the filesystem. Modifying the
+// clone never affects the original and vice versa.
+func (fs *Filesystem) Clone() *Filesystem {
+	return fs.copy((*Node).clone)
+}
+
+// Snapshot returns a copy-on-write copy of the filesystem. File data is
+// shared between the snapshot and the original until either side modifies
+// a file, at which point that file's data is copied. Like with Clone,
+// modifications to one side are never visible on the other.
+func (fs *Filesystem) Snapshot() *Filesystem {
+	return fs.copy((*Node).snapshot)
+}
+
+// copy returns a new filesystem holding the nodes returned by dup for
+// every node of fs.
+func (fs *Filesystem) copy(dup func(*Node, *Filesystem) *Node) *Filesystem {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	c := &Filesystem{
+		clock: fs.clock,
+		files: make(map[string]*Node, len(fs.files)),
+	}
+	c.quota.Store(fs.quota.Load())
+	c.maxFileSize.Store(fs.maxFileSize.Load())
+	c.inodes.Store(fs.inodes.Load())
+	c.root = dup(fs.root, c)
+	if fs.folded != nil {
+		c.folded = make(map[string]string, len(fs.folded))
+	}
+	// Names sharing a node keep sharing the copy.
+	copies := make(map[*Node]*Node, len(fs.files))
+	for k, f := range fs.files {
+		n, ok := copies[f]
+		if !ok {
+			n = dup(f, c)
+			copies[f] = n
+			// Only count the data that was copied, not that of removed
+			// files which are still open in fs.
+			n.Mu.RLock()
+			c.used.Add(int64(n.Data.Len()))
+			n.Mu.RUnlock()
+		}
+		c.put(k, n)
+	}
+	return c
+}
+
+// MapFile maps a file from the host system into the guest system,
+// preserving its mode and modification time.
+func (fs *Filesystem) MapFile(hostname, guestname string) error {
+	f, err := os.Open(hostname)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	fg, err := fs.CreateSize(guestname, int(stat.Size()))
+	if err != nil {
+		return err
+	}
+	defer fg.Close()
+	if _, err := io.Copy(fg, f); err != nil {
+		return err
+	}
+	// Set the mode through the node itself, the name might have been
+	// renamed or removed in the meantime.
+	fg.node.Mu.Lock()
+	fg.node.Mode = stat.Mode()
+	fg.node.ModTime = stat.ModTime()
+	fg.node.Mu.Unlock()
+	return nil
+}
+
+// MapDir recursively maps the host directory hostdir into the guest
+// directory guestdir. Symbolic links are followed if followSymlinks is
+// set and skipped otherwise.
+func (fs *Filesystem) MapDir(hostdir, guestdir string, followSymlinks bool) error {
+	return filepath.WalkDir(hostdir, func(p string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(hostdir, p)
+		if err != nil {
+			return err
+		}
+		guestname := path.Join(guestdir, filepath.ToSlash(rel))
+		if d.Type()&os.ModeSymlink != 0 {
+			if !followSymlinks {
+				return nil
+			}
+			stat, err := os.Stat(p)
+			if err != nil {
+				return err
+			}
+			if stat.IsDir() {
+				return fs.MapDir(p, guestname, followSymlinks)
+			}
+			return fs.MapFile(p, guestname)
+		}
+		if d.IsDir() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if err := fs.MkdirAll(guestname, info.Mode()&modeBits); err != nil {
+				return err
+			}
+			if guestname == "." {
+				return nil
+			}
+			return fs.Chtimes(guestname, info.ModTime(), info.ModTime())
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return fs.MapFile(p, guestname)
+	})
+}
+
+// FromDir creates a new Filesystem holding a copy of every file and
+// directory under the host directory hostdir, with their relative paths,
+// modes and modification times. Symbolic links are skipped.
+func FromDir(hostdir string) (*Filesystem, error) {
+	fs := New()
+	if err := fs.MapDir(hostdir, ".", false); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// UnmapFile copies a file from the guest system out to the host system.
+func (fs *Filesystem) UnmapFile(guestname, hostname string) error {
+	fg, err := fs.OpenFile(guestname, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer fg.Close()
+	if fg.node.IsDir {
+		return &os.PathError{
+			Op:   "read",
+			Err:  syscall.EISDIR,
+			Path: guestname,
+		}
+	}
+	stat, err := fg.Stat()
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(hostname)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, fg); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
 		return err
 	}
-	return fs.Chmod(guestname, stat.Mode())
+	return os.Chmod(hostname, stat.Mode())
 }
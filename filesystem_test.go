@@ -0,0 +1,2008 @@
+package ramfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"math/rand"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestOffsetIsolation(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f1, err := fs.OpenFile("foo", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f1.Close()
+	f2, err := fs.OpenFile("foo", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f2.Close()
+	b := make([]byte, 6)
+	if n, err := f1.Read(b); err != nil || string(b[:n]) != "hello " {
+		t.Fatalf("f1.read() = %q, %v, want %q", b[:n], err, "hello ")
+	}
+	if n, err := f2.Read(b[:5]); err != nil || string(b[:n]) != "hello" {
+		t.Fatalf("f2.read() = %q, %v, want %q", b[:n], err, "hello")
+	}
+	if off, _ := f1.Seek(0, io.SeekCurrent); off != 6 {
+		t.Fatalf("f1.seek(0, current) = %d, want %d", off, 6)
+	}
+	// Writes through f2 at its own offset show up in reads through f1.
+	if _, err := f2.Write([]byte(", WORLD")); err != nil {
+		t.Fatal(err)
+	}
+	if off, _ := f2.Seek(0, io.SeekCurrent); off != 12 {
+		t.Fatalf("f2.seek(0, current) = %d, want %d", off, 12)
+	}
+	got, err := io.ReadAll(f1)
+	if err != nil || string(got) != " WORLD" {
+		t.Fatalf("f1.readall() = %q, %v, want %q", got, err, " WORLD")
+	}
+}
+
+func TestOpenAppendTrunc(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("log", []byte("old data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.OpenFile("log", os.O_WRONLY|os.O_APPEND|os.O_TRUNC, 0)
+	if err != nil {
+		t.Fatalf("openfile(log, O_APPEND|O_TRUNC) = %v", err)
+	}
+	if _, err := f.Write([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if b, err := fs.ReadFile("log"); err != nil || string(b) != "new" {
+		t.Fatalf("readfile(log) = %q, %v, want %q", b, err, "new")
+	}
+}
+
+func TestOpenAppend(t *testing.T) {
+	fs := New()
+	fd, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	fd, err = fs.OpenFile("foo", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte(" world")); err != nil {
+		t.Fatal(err)
+	}
+	want := "hello world"
+	if got := fd.node.Data.String(); got != want {
+		t.Fatalf("data = %q, want %q", got, want)
+	}
+}
+
+func TestOpenExclusive(t *testing.T) {
+	fs := New()
+	if _, err := fs.OpenFile("foo", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666); err != nil {
+		t.Fatalf("open(new, excl) = %v", err)
+	}
+	if _, err := fs.OpenFile("foo", os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("open(existing, excl) = %v, want %v", err, os.ErrExist)
+	}
+	if _, err := fs.OpenFile("foo", os.O_RDWR|os.O_CREATE, 0666); err != nil {
+		t.Fatalf("open(existing) = %v", err)
+	}
+}
+
+func TestOpenAccessMode(t *testing.T) {
+	tests := []struct {
+		flag              int
+		readErr, writeErr error
+	}{
+		{flag: os.O_RDONLY, writeErr: os.ErrPermission},
+		{flag: os.O_WRONLY, readErr: os.ErrPermission},
+		{flag: os.O_RDWR},
+	}
+	fs := New()
+	fd, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range tests {
+		fd, err := fs.OpenFile("foo", tc.flag, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fd.Write([]byte("x")); !errors.Is(err, tc.writeErr) {
+			t.Errorf("open(%#x).write() = %v, want %v", tc.flag, err, tc.writeErr)
+		}
+		if _, err := fd.WriteAt([]byte("x"), 0); !errors.Is(err, tc.writeErr) {
+			t.Errorf("open(%#x).writeat() = %v, want %v", tc.flag, err, tc.writeErr)
+		}
+		if _, err := fd.ReadAt(make([]byte, 1), 0); !errors.Is(err, tc.readErr) {
+			t.Errorf("open(%#x).readat() = %v, want %v", tc.flag, err, tc.readErr)
+		}
+		fd.Seek(0, io.SeekStart)
+		if _, err := fd.Read(make([]byte, 1)); !errors.Is(err, tc.readErr) {
+			t.Errorf("open(%#x).read() = %v, want %v", tc.flag, err, tc.readErr)
+		}
+	}
+}
+
+func TestOpenTruncate(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.OpenFile("foo", os.O_RDONLY|os.O_TRUNC, 0); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("openfile(foo, O_RDONLY|O_TRUNC) = %v, want %v", err, os.ErrInvalid)
+	}
+	if b, err := fs.ReadFile("foo"); err != nil || string(b) != "hello" {
+		t.Fatalf("readfile(foo) = %q, %v, want %q", b, err, "hello")
+	}
+	if _, err := fs.OpenFile("foo", os.O_RDWR|os.O_TRUNC, 0); err != nil {
+		t.Fatalf("openfile(foo, O_RDWR|O_TRUNC) = %v, want nil", err)
+	}
+	if b, err := fs.ReadFile("foo"); err != nil || len(b) != 0 {
+		t.Fatalf("readfile(foo) = %q, %v, want empty", b, err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	fs := New()
+	fd, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("foo"); err != nil {
+		t.Fatalf("remove(foo) = %v", err)
+	}
+	if _, err := fs.Open("foo"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("open(foo) = %v, want %v", err, os.ErrNotExist)
+	}
+	if err := fs.Remove("foo"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("remove(foo) = %v, want %v", err, os.ErrNotExist)
+	}
+	fd, err = fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := fd.Stat(); st.Size() != 0 {
+		t.Fatalf("size = %d, want %d", st.Size(), 0)
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/b/c", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Mkdir("ab", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "a/foo", "a/b/foo", "a/b/c/foo", "ab/foo"} {
+		if err := fs.WriteFile(name, []byte("foo"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.RemoveAll("a/b"); err != nil {
+		t.Fatalf("removeall(a/b) = %v", err)
+	}
+	var names []string
+	err := fs.WalkDir(".", func(name string, d iofs.DirEntry, err error) error {
+		names = append(names, name)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".", "a", "a/foo", "ab", "ab/foo", "foo"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("names = %q, want %q", names, want)
+	}
+	if _, bytes := fs.Usage(); bytes != 9 {
+		t.Fatalf("usage() = %d bytes, want %d", bytes, 9)
+	}
+	if err := fs.RemoveAll("a/b"); err != nil {
+		t.Fatalf("removeall(a/b) = %v on missing path, want nil", err)
+	}
+	if err := fs.RemoveAll("foo"); err != nil || fs.Exists("foo") {
+		t.Fatalf("removeall(foo) = %v, exists = %v", err, fs.Exists("foo"))
+	}
+	if err := fs.RemoveAll("."); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("removeall(.) = %v, want %v", err, os.ErrInvalid)
+	}
+}
+
+func TestRename(t *testing.T) {
+	fs := New()
+	for _, name := range []string{"foo", "bar"} {
+		fd, err := fs.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fd.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Rename("foo", "bar"); err != nil {
+		t.Fatalf("rename(foo, bar) = %v", err)
+	}
+	if _, err := fs.Open("foo"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("open(foo) = %v, want %v", err, os.ErrNotExist)
+	}
+	fd, err := fs.OpenFile("bar", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := fd.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Name() != "bar" {
+		t.Fatalf("name = %q, want %q", st.Name(), "bar")
+	}
+	if got := fd.node.Data.String(); got != "foo" {
+		t.Fatalf("data = %q, want %q", got, "foo")
+	}
+	var le *os.LinkError
+	if err := fs.Rename("foo", "baz"); !errors.As(err, &le) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("rename(foo, baz) = %v, want *os.LinkError(%v)", err, os.ErrNotExist)
+	}
+}
+
+func TestRenameDir(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a/x", "a/sub/y"} {
+		if err := fs.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Rename("a", "b"); err != nil {
+		t.Fatalf("rename(a, b) = %v", err)
+	}
+	for _, name := range []string{"a", "a/x", "a/sub/y"} {
+		if fs.Exists(name) {
+			t.Fatalf("exists(%s) = true after rename, want false", name)
+		}
+	}
+	for _, name := range []string{"x", "sub/y"} {
+		b, err := fs.ReadFile("b/" + name)
+		if err != nil || string(b) != "a/"+name {
+			t.Fatalf("readfile(b/%s) = %q, %v, want %q", name, b, err, "a/"+name)
+		}
+	}
+	if err := fs.Rename("b", "b/sub/c"); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("rename(b, b/sub/c) = %v, want %v", err, os.ErrInvalid)
+	}
+	// An empty directory can be replaced by a directory.
+	if err := fs.Mkdir("empty", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Rename("b/sub", "empty"); err != nil {
+		t.Fatalf("rename(b/sub, empty) = %v", err)
+	}
+	if b, err := fs.ReadFile("empty/y"); err != nil || string(b) != "a/sub/y" {
+		t.Fatalf("readfile(empty/y) = %q, %v, want %q", b, err, "a/sub/y")
+	}
+}
+
+func TestRenameReplaceDir(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("d/sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Mkdir("e", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"f", "d/sub/x"} {
+		if err := fs.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		oldpath, newpath string
+		want             error
+	}{
+		{"f", "d", syscall.EISDIR},
+		{"f", "e", syscall.EISDIR},
+		{"e", "d", syscall.ENOTEMPTY},
+		{"d", "f", syscall.ENOTDIR},
+	}
+	for _, tc := range tests {
+		var le *os.LinkError
+		if err := fs.Rename(tc.oldpath, tc.newpath); !errors.As(err, &le) || !errors.Is(err, tc.want) {
+			t.Fatalf("rename(%s, %s) = %v, want *os.LinkError(%v)", tc.oldpath, tc.newpath, err, tc.want)
+		}
+	}
+	if st, err := fs.Stat("d"); err != nil || !st.IsDir() {
+		t.Fatalf("stat(d) = %v, %v, want directory", st, err)
+	}
+	if !fs.Exists("d/sub/x") || !fs.Exists("f") {
+		t.Fatalf("failed renames changed the tree")
+	}
+}
+
+func TestRenameParent(t *testing.T) {
+	fs := New()
+	for _, name := range []string{"a", "file"} {
+		if err := fs.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		newpath string
+		want    error
+	}{
+		{"file/b", syscall.ENOTDIR},
+		{"missing/dir/a", os.ErrNotExist},
+	}
+	for _, tc := range tests {
+		var le *os.LinkError
+		if err := fs.Rename("a", tc.newpath); !errors.As(err, &le) || !errors.Is(err, tc.want) {
+			t.Fatalf("rename(a, %s) = %v, want *os.LinkError(%v)", tc.newpath, err, tc.want)
+		}
+		if fs.Exists(tc.newpath) {
+			t.Fatalf("exists(%s) = true after failed rename, want false", tc.newpath)
+		}
+	}
+	if fs.Exists("missing") {
+		t.Fatalf("exists(missing) = true after failed rename, want false")
+	}
+}
+
+func TestFS(t *testing.T) {
+	fs := New()
+	for _, name := range []string{"foo", "bar", "baz.txt"} {
+		fd, err := fs.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fd.Write([]byte("hello " + name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fstest.TestFS(fs, "foo", "bar", "baz.txt"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/b", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "a/bar", "a/b/baz", "a/b/qux"} {
+		if _, err := fs.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		dir  string
+		want []string
+	}{
+		{dir: ".", want: []string{"a/", "foo"}},
+		{dir: "a", want: []string{"b/", "bar"}},
+		{dir: "a/b", want: []string{"baz", "qux"}},
+	}
+	for _, tc := range tests {
+		entries, err := fs.ReadDir(tc.dir)
+		if err != nil {
+			t.Fatalf("readdir(%q) = %v", tc.dir, err)
+		}
+		var got []string
+		for _, e := range entries {
+			name := e.Name()
+			if e.IsDir() {
+				name += "/"
+			}
+			got = append(got, name)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("readdir(%q) = %q, want %q", tc.dir, got, tc.want)
+		}
+	}
+	if _, err := fs.ReadDir("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("readdir(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+	if err := fstest.TestFS(fs, "foo", "a/bar", "a/b/baz", "a/b/qux"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReaddir(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/d", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a/b", "a/c", "a/e"} {
+		if err := fs.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := fs.OpenFile("a", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for {
+		infos, err := f.Readdir(3)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(infos) > 3 {
+			t.Fatalf("readdir(3) = %d entries, want at most 3", len(infos))
+		}
+		for _, info := range infos {
+			got = append(got, fmt.Sprintf("%s %d %v", info.Name(), info.Size(), info.IsDir()))
+		}
+	}
+	want := []string{"b 3 false", "c 3 false", "d 0 true", "e 3 false"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("readdir() = %q, want %q", got, want)
+	}
+	f, err = fs.OpenFile("a", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if infos, err := f.Readdir(0); err != nil || len(infos) != 4 {
+		t.Fatalf("readdir(0) = %d entries, %v, want %d", len(infos), err, 4)
+	}
+	if infos, err := f.Readdir(0); err != nil || len(infos) != 0 {
+		t.Fatalf("readdir(0) = %d entries, %v at end, want %d", len(infos), err, 0)
+	}
+}
+
+func TestReaddirnames(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a/b", "a/c", "a/d/e", "a/f"} {
+		if err := fs.MkdirAll(path.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f, err := fs.OpenFile("a", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pages [][]string
+	for {
+		names, err := f.Readdirnames(3)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, names)
+	}
+	want := [][]string{{"b", "c", "d"}, {"f"}}
+	if !reflect.DeepEqual(pages, want) {
+		t.Fatalf("readdirnames(3) = %q, want %q", pages, want)
+	}
+	fb, err := fs.OpenFile("a/b", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fb.Readdirnames(0); !errors.Is(err, syscall.ENOTDIR) {
+		t.Fatalf("readdirnames(0) = %v on file, want %v", err, syscall.ENOTDIR)
+	}
+}
+
+func TestMkdir(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatalf("mkdir(a) = %v", err)
+	}
+	if err := fs.Mkdir("a", 0755); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("mkdir(a) = %v, want %v", err, os.ErrExist)
+	}
+	if err := fs.Mkdir("b/c", 0755); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("mkdir(b/c) = %v, want %v", err, os.ErrNotExist)
+	}
+	if _, err := fs.Create("a/foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Mkdir("a/foo", 0755); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("mkdir(a/foo) = %v, want %v", err, os.ErrExist)
+	}
+	if err := fs.Mkdir("a/foo/bar", 0755); !errors.Is(err, syscall.ENOTDIR) {
+		t.Fatalf("mkdir(a/foo/bar) = %v, want %v", err, syscall.ENOTDIR)
+	}
+	fd, err := fs.OpenFile("a", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := fd.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.IsDir() || st.Mode() != os.ModeDir|0755 {
+		t.Fatalf("stat(a).Mode() = %v, want %v", st.Mode(), os.ModeDir|0755)
+	}
+}
+
+func TestMkdirAll(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/b/c", 0700); err != nil {
+		t.Fatalf("mkdirall(a/b/c) = %v", err)
+	}
+	for _, name := range []string{"a", "a/b", "a/b/c"} {
+		f, ok := fs.files[name]
+		if !ok || !f.IsDir {
+			t.Fatalf("%q is not a directory", name)
+		}
+	}
+	if err := fs.MkdirAll("a/b", 0700); err != nil {
+		t.Fatalf("mkdirall(a/b) = %v", err)
+	}
+	if _, err := fs.Create("a/foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.MkdirAll("a/foo", 0700); !errors.Is(err, syscall.ENOTDIR) {
+		t.Fatalf("mkdirall(a/foo) = %v, want %v", err, syscall.ENOTDIR)
+	}
+	if err := fs.MkdirAll("a/foo/bar", 0700); !errors.Is(err, syscall.ENOTDIR) {
+		t.Fatalf("mkdirall(a/foo/bar) = %v, want %v", err, syscall.ENOTDIR)
+	}
+}
+
+func TestCreateParent(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Create("a/foo"); err != nil {
+		t.Fatalf("create(a/foo) = %v", err)
+	}
+	if _, err := fs.Create("b/foo"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("create(b/foo) = %v, want %v", err, os.ErrNotExist)
+	}
+	if _, err := fs.Create("a/foo/bar"); !errors.Is(err, syscall.ENOTDIR) {
+		t.Fatalf("create(a/foo/bar) = %v, want %v", err, syscall.ENOTDIR)
+	}
+}
+
+func TestStat(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := fs.Create("a/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	st, err := fs.Stat("a/foo")
+	if err != nil {
+		t.Fatalf("stat(a/foo) = %v", err)
+	}
+	if st.Name() != "foo" || st.Size() != 5 || st.IsDir() {
+		t.Fatalf("stat(a/foo) = %q %d %v, want %q %d %v", st.Name(), st.Size(), st.IsDir(), "foo", 5, false)
+	}
+	st, err = fs.Stat("a")
+	if err != nil {
+		t.Fatalf("stat(a) = %v", err)
+	}
+	if !st.IsDir() {
+		t.Fatalf("stat(a).IsDir() = false, want true")
+	}
+	if _, err := fs.Stat("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("stat(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestStatFS(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var fsys iofs.FS = fs
+	if _, ok := fsys.(iofs.StatFS); !ok {
+		t.Fatalf("%T does not implement fs.StatFS", fsys)
+	}
+	st, err := iofs.Stat(fsys, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Name() != "foo" || st.Size() != 5 {
+		t.Fatalf("stat(foo) = %q %d, want %q %d", st.Name(), st.Size(), "foo", 5)
+	}
+	if _, err := iofs.Stat(fsys, "missing"); !errors.Is(err, iofs.ErrNotExist) {
+		t.Fatalf("stat(missing) = %v, want %v", err, iofs.ErrNotExist)
+	}
+}
+
+func TestExists(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/b", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("a/b/foo", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		".":       true,
+		"a":       true,
+		"a/b":     true,
+		"a/b/foo": true,
+		"a/foo":   false,
+		"missing": false,
+	} {
+		if got := fs.Exists(name); got != want {
+			t.Fatalf("exists(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("./a/", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("a//b.txt", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a/b.txt", "./a/b.txt", "a//b.txt", "a/./b.txt", "a/../a/b.txt"} {
+		f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+		if err != nil {
+			t.Fatalf("openfile(%q) = %v", name, err)
+		}
+		if b, err := io.ReadAll(f); err != nil || string(b) != "hello" {
+			t.Fatalf("read(%q) = %q, %v, want %q", name, b, err, "hello")
+		}
+		if _, err := fs.Stat(name); err != nil {
+			t.Fatalf("stat(%q) = %v", name, err)
+		}
+	}
+	if err := fs.Chmod("./a/b.txt", 0600); err != nil {
+		t.Fatal(err)
+	}
+	if st, err := fs.Stat("a/b.txt"); err != nil || st.Mode() != 0600 {
+		t.Fatalf("stat(a/b.txt) = %v, %v, want mode %v", st, err, os.FileMode(0600))
+	}
+	if err := fs.Rename("a//b.txt", "./a/c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("a/./c.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := fs.Usage(); files != 1 {
+		t.Fatalf("usage() = %v files, want %v", files, 1)
+	}
+	for _, name := range []string{"/a", "..", "../a", "a/../../b"} {
+		if _, err := fs.Stat(name); !errors.Is(err, os.ErrInvalid) {
+			t.Fatalf("stat(%q) = %v, want %v", name, err, os.ErrInvalid)
+		}
+		if _, err := fs.Create(name); !errors.Is(err, os.ErrInvalid) {
+			t.Fatalf("create(%q) = %v, want %v", name, err, os.ErrInvalid)
+		}
+		if err := fs.Remove(name); !errors.Is(err, os.ErrInvalid) {
+			t.Fatalf("remove(%q) = %v, want %v", name, err, os.ErrInvalid)
+		}
+		if err := fs.Rename("a", name); !errors.Is(err, os.ErrInvalid) {
+			t.Fatalf("rename(a, %q) = %v, want %v", name, err, os.ErrInvalid)
+		}
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	fs := New()
+	fd, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.ReadFile("foo")
+	if err != nil {
+		t.Fatalf("readfile(foo) = %v", err)
+	}
+	if string(b) != "hello" {
+		t.Fatalf("readfile(foo) = %q, want %q", b, "hello")
+	}
+	b[0] = 'J'
+	if got := fd.node.Data.String(); got != "hello" {
+		t.Fatalf("data = %q, want %q", got, "hello")
+	}
+	var pe *os.PathError
+	if _, err := fs.ReadFile("missing"); !errors.As(err, &pe) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("readfile(missing) = %v, want *os.PathError(%v)", err, os.ErrNotExist)
+	}
+}
+
+func TestReader(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := fs.Reader("foo")
+	if err != nil {
+		t.Fatalf("reader(foo) = %v", err)
+	}
+	// The reader keeps the contents from the time it was created.
+	f, err := fs.OpenFile("foo", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("jelly world")); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	b, err := io.ReadAll(r)
+	if err != nil || string(b) != "hello" {
+		t.Fatalf("readall() = %q, %v, want %q", b, err, "hello")
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("close() = %v", err)
+	}
+	if b, _ := fs.ReadFile("foo"); string(b) != "jelly world" {
+		t.Fatalf("readfile(foo) = %q, want %q", b, "jelly world")
+	}
+	if _, err := fs.Reader("."); !errors.Is(err, syscall.EISDIR) {
+		t.Fatalf("reader(.) = %v, want %v", err, syscall.EISDIR)
+	}
+	if _, err := fs.Reader("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("reader(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.Bytes("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Fatalf("bytes(foo) = %q, want %q", b, "hello")
+	}
+	f, err := fs.OpenFile("foo", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte(" world")); err != nil {
+		t.Fatal(err)
+	}
+	b, err = fs.Bytes("foo")
+	if err != nil || string(b) != "hello world" {
+		t.Fatalf("bytes(foo) = %q, %v, want %q", b, err, "hello world")
+	}
+	// Bytes does not copy, so overwriting the file in place shows
+	// through the view.
+	g, err := fs.OpenFile("foo", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.WriteAt([]byte("j"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "jello world" {
+		t.Fatalf("bytes(foo) = %q after writeat, want %q", b, "jello world")
+	}
+	if _, err := fs.Bytes("."); !errors.Is(err, syscall.EISDIR) {
+		t.Fatalf("bytes(.) = %v, want %v", err, syscall.EISDIR)
+	}
+	if _, err := fs.Bytes("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("bytes(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello world"), 0644); err != nil {
+		t.Fatalf("writefile(foo) = %v", err)
+	}
+	if err := fs.WriteFile("foo", []byte("bye"), 0644); err != nil {
+		t.Fatalf("writefile(foo) = %v", err)
+	}
+	b, err := fs.ReadFile("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "bye" {
+		t.Fatalf("readfile(foo) = %q, want %q", b, "bye")
+	}
+	st, err := fs.Stat("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode() != 0644 {
+		t.Fatalf("stat(foo).Mode() = %v, want %v", st.Mode(), os.FileMode(0644))
+	}
+	if err := fs.WriteFile("a/foo", nil, 0644); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("writefile(a/foo) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestUnmapFile(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	host := filepath.Join(t.TempDir(), "foo")
+	if err := fs.UnmapFile("foo", host); err != nil {
+		t.Fatalf("unmapfile(foo) = %v", err)
+	}
+	b, err := os.ReadFile(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello" {
+		t.Fatalf("host data = %q, want %q", b, "hello")
+	}
+	st, err := os.Stat(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode() != 0600 {
+		t.Fatalf("host mode = %v, want %v", st.Mode(), os.FileMode(0600))
+	}
+	if err := fs.UnmapFile("missing", host); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("unmapfile(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+	if err := fs.Mkdir("dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	hostdir := filepath.Join(t.TempDir(), "dir")
+	var pe *os.PathError
+	if err := fs.UnmapFile("dir", hostdir); !errors.As(err, &pe) || !errors.Is(err, syscall.EISDIR) {
+		t.Fatalf("unmapfile(dir) = %v, want *os.PathError(%v)", err, syscall.EISDIR)
+	}
+	if _, err := os.Stat(hostdir); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("host stat(dir) = %v after failed unmapfile, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestMapDir(t *testing.T) {
+	host := t.TempDir()
+	files := map[string]string{
+		"foo":       "foo",
+		"a/bar":     "bar",
+		"a/b/baz":   "baz",
+		"a/b/c/qux": "qux",
+	}
+	for name, data := range files {
+		p := filepath.Join(host, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(host, "foo"), filepath.Join(host, "link")); err != nil {
+		t.Fatal(err)
+	}
+	fs := New()
+	if err := fs.Mkdir("guest", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.MapDir(host, "guest", false); err != nil {
+		t.Fatalf("mapdir() = %v", err)
+	}
+	for name, want := range files {
+		b, err := fs.ReadFile("guest/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != want {
+			t.Errorf("readfile(%q) = %q, want %q", name, b, want)
+		}
+	}
+	if st, err := fs.Stat("guest/a/b"); err != nil || !st.IsDir() {
+		t.Fatalf("stat(guest/a/b) = %v, %v, want directory", st, err)
+	}
+	if _, err := fs.Stat("guest/link"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("stat(guest/link) = %v, want %v", err, os.ErrNotExist)
+	}
+	if err := fs.MapDir(host, ".", true); err != nil {
+		t.Fatalf("mapdir() = %v", err)
+	}
+	if b, err := fs.ReadFile("link"); err != nil || string(b) != "foo" {
+		t.Fatalf("readfile(link) = %q, %v, want %q", b, err, "foo")
+	}
+}
+
+func TestCreateSize(t *testing.T) {
+	fs := New()
+	f, err := fs.CreateSize("foo", 1<<16)
+	if err != nil {
+		t.Fatalf("createsize(foo) = %v", err)
+	}
+	if st, _ := f.Stat(); st.Size() != 0 {
+		t.Fatalf("stat(foo).Size() = %d, want 0", st.Size())
+	}
+	chunk := make([]byte, 1<<10)
+	// AllocsPerRun calls the function once more to warm up.
+	allocs := testing.AllocsPerRun(1<<6-1, func() {
+		if _, err := f.Write(chunk); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if st, _ := f.Stat(); st.Size() != 1<<16 {
+		t.Fatalf("stat(foo).Size() = %d, want %d", st.Size(), 1<<16)
+	}
+	if allocs != 0 {
+		t.Fatalf("writing %d bytes allocated %v times, want 0", 1<<16, allocs)
+	}
+	if _, err := fs.CreateSize("missing/foo", 1); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("createsize(missing/foo) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func BenchmarkMapFile(b *testing.B) {
+	host := filepath.Join(b.TempDir(), "foo")
+	if err := os.WriteFile(host, make([]byte, 8<<20), 0644); err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(8 << 20)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := New().MapFile(host, "foo"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkImport(b *testing.B, create func(fs *Filesystem, name string, size int) (*File, error)) {
+	chunk := make([]byte, 32<<10)
+	const size = 8 << 20
+	b.SetBytes(size)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		f, err := create(New(), "foo", size)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for n := 0; n < size; n += len(chunk) {
+			if _, err := f.Write(chunk); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkImportCreate(b *testing.B) {
+	benchmarkImport(b, func(fs *Filesystem, name string, size int) (*File, error) {
+		return fs.Create(name)
+	})
+}
+
+func BenchmarkImportCreateSize(b *testing.B) {
+	benchmarkImport(b, (*Filesystem).CreateSize)
+}
+
+func TestFromDir(t *testing.T) {
+	host := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(host, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(host, "a", "b", "run.sh")
+	if err := os.WriteFile(p, []byte("#!/bin/sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(p, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	fs, err := FromDir(host)
+	if err != nil {
+		t.Fatalf("FromDir() = %v", err)
+	}
+	b, err := fs.ReadFile("a/b/run.sh")
+	if err != nil || string(b) != "#!/bin/sh" {
+		t.Fatalf("readfile(a/b/run.sh) = %q, %v, want %q", b, err, "#!/bin/sh")
+	}
+	st, err := fs.Stat("a/b/run.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode() != 0755 {
+		t.Fatalf("mode = %v, want %v", st.Mode(), os.FileMode(0755))
+	}
+	if !st.ModTime().Equal(mtime) {
+		t.Fatalf("mtime = %v, want %v", st.ModTime(), mtime)
+	}
+	if st, err := fs.Stat("a/b"); err != nil || !st.IsDir() {
+		t.Fatalf("stat(a/b) = %v, %v, want directory", st, err)
+	}
+	if _, err := FromDir(filepath.Join(host, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("FromDir(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestMapFileRename(t *testing.T) {
+	host := filepath.Join(t.TempDir(), "foo")
+	if err := os.WriteFile(host, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		fs := New()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for j := 0; j < 100; j++ {
+				fs.Rename("foo", "bar")
+			}
+		}()
+		if err := fs.MapFile(host, "foo"); err != nil {
+			t.Fatalf("mapfile() = %v", err)
+		}
+		<-done
+		st, err := fs.Stat("foo")
+		if err != nil {
+			st, err = fs.Stat("bar")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Mode() != 0600 {
+			t.Fatalf("mode = %v, want %v", st.Mode(), os.FileMode(0600))
+		}
+	}
+}
+
+func TestChmod(t *testing.T) {
+	fs := New()
+	if _, err := fs.Create("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Chmod("foo", 0600); err != nil {
+		t.Fatalf("chmod(foo) = %v", err)
+	}
+	if st, _ := fs.Stat("foo"); st.Mode() != 0600 {
+		t.Fatalf("stat(foo).Mode() = %v, want %v", st.Mode(), os.FileMode(0600))
+	}
+	if err := fs.Chmod("missing", 0600); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("chmod(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestChmodSpecialBits(t *testing.T) {
+	fs := New()
+	if _, err := fs.Create("foo"); err != nil {
+		t.Fatal(err)
+	}
+	mode := os.ModeSetuid | os.ModeSticky | 0755
+	if err := fs.Chmod("foo", mode); err != nil {
+		t.Fatalf("chmod(foo) = %v", err)
+	}
+	if st, _ := fs.Stat("foo"); st.Mode() != mode {
+		t.Fatalf("stat(foo).Mode() = %v, want %v", st.Mode(), mode)
+	}
+	// Special bits do not get in the way of the permission check.
+	f, err := fs.OpenFile("foo", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("openfile(foo) = %v", err)
+	}
+	f.Close()
+	if err := fs.WriteFile("bar", nil, os.ModeSetgid|0644); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := fs.Stat("bar"); st.Mode() != os.ModeSetgid|0644 {
+		t.Fatalf("stat(bar).Mode() = %v, want %v", st.Mode(), os.ModeSetgid|0644)
+	}
+	if err := fs.Mkdir("tmp", os.ModeSticky|0777); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := fs.Stat("tmp"); st.Mode() != os.ModeDir|os.ModeSticky|0777 {
+		t.Fatalf("stat(tmp).Mode() = %v, want %v", st.Mode(), os.ModeDir|os.ModeSticky|0777)
+	}
+	// Chmod leaves the type of the file alone.
+	if err := fs.Chmod("tmp", 0700); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := fs.Stat("tmp"); st.Mode() != os.ModeDir|0700 || !st.IsDir() {
+		t.Fatalf("stat(tmp).Mode() = %v, want %v", st.Mode(), os.ModeDir|0700)
+	}
+}
+
+func TestChown(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	owner := func() (int, int) {
+		st, err := fs.Stat("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		sys := st.Sys().(*SysInfo)
+		return sys.Uid, sys.Gid
+	}
+	if uid, gid := owner(); uid != 0 || gid != 0 {
+		t.Fatalf("uid, gid = %d, %d, want %d, %d", uid, gid, 0, 0)
+	}
+	if err := fs.Chown("foo", 1000, 100); err != nil {
+		t.Fatal(err)
+	}
+	if uid, gid := owner(); uid != 1000 || gid != 100 {
+		t.Fatalf("uid, gid = %d, %d, want %d, %d", uid, gid, 1000, 100)
+	}
+	if err := fs.Chown("foo", -1, 200); err != nil {
+		t.Fatal(err)
+	}
+	if uid, gid := owner(); uid != 1000 || gid != 200 {
+		t.Fatalf("uid, gid = %d, %d, want %d, %d", uid, gid, 1000, 200)
+	}
+	if err := fs.Chown("missing", 0, 0); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("chown(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestOpenPermission(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0400); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.OpenFile("foo", os.O_RDONLY, 0); err != nil {
+		t.Fatalf("open(foo, O_RDONLY) = %v", err)
+	}
+	for _, flag := range []int{os.O_WRONLY, os.O_RDWR} {
+		if _, err := fs.OpenFile("foo", flag, 0); !errors.Is(err, os.ErrPermission) {
+			t.Fatalf("open(foo, %#x) = %v, want %v", flag, err, os.ErrPermission)
+		}
+	}
+	if err := fs.WriteFile("bar", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Create("bar"); err != nil {
+		t.Fatalf("create(bar) = %v", err)
+	}
+}
+
+func TestTruncateByName(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		size int64
+		want string
+	}{
+		{size: 5, want: "hello"},
+		{size: 7, want: "hello\x00\x00"},
+		{size: 0, want: ""},
+	} {
+		if err := fs.Truncate("foo", tc.size); err != nil {
+			t.Fatalf("truncate(foo, %d) = %v", tc.size, err)
+		}
+		b, err := fs.ReadFile("foo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.want {
+			t.Fatalf("truncate(foo, %d) = %q, want %q", tc.size, b, tc.want)
+		}
+	}
+	if err := fs.Truncate("missing", 0); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("truncate(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+	if err := fs.WriteFile("ro", []byte("hello"), 0444); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Truncate("ro", 0); !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("truncate(ro) = %v, want %v", err, os.ErrPermission)
+	}
+	if b, err := fs.ReadFile("ro"); err != nil || string(b) != "hello" {
+		t.Fatalf("readfile(ro) = %q, %v after failed truncate, want %q", b, err, "hello")
+	}
+}
+
+func TestAccessTime(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now()
+	if _, err := fs.ReadFile("foo"); err != nil {
+		t.Fatal(err)
+	}
+	st, err := fs.Stat("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if atime := st.Sys().(*SysInfo).AccessTime; atime.Before(before) {
+		t.Fatalf("atime = %v, want after %v", atime, before)
+	}
+}
+
+func TestMove(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/b", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Mkdir("c", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "a/bar", "a/b/baz"} {
+		if err := fs.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Move("foo", "c/foo"); err != nil {
+		t.Fatalf("move(foo, c/foo) = %v", err)
+	}
+	if err := fs.Move("a", "c/a"); err != nil {
+		t.Fatalf("move(a, c/a) = %v", err)
+	}
+	for name, want := range map[string]string{
+		"c/foo":     "foo",
+		"c/a/bar":   "a/bar",
+		"c/a/b/baz": "a/b/baz",
+	} {
+		if b, err := fs.ReadFile(name); err != nil || string(b) != want {
+			t.Fatalf("readfile(%q) = %q, %v, want %q", name, b, err, want)
+		}
+	}
+	for _, name := range []string{"foo", "a", "a/bar", "a/b/baz"} {
+		if fs.Exists(name) {
+			t.Fatalf("exists(%q) = true after move, want false", name)
+		}
+	}
+	if err := fs.Move("c/foo", "missing/foo"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("move(c/foo, missing/foo) = %v, want %v", err, os.ErrNotExist)
+	}
+	if err := fs.Move("c/foo", "c/foo/bar"); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("move(c/foo, c/foo/bar) = %v, want %v", err, os.ErrInvalid)
+	}
+	if err := fs.Move("c", "c/a/c"); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("move(c, c/a/c) = %v, want %v", err, os.ErrInvalid)
+	}
+	if !fs.Exists("c/foo") {
+		t.Fatalf("exists(c/foo) = false after failed move, want true")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("bar", []byte("something longer"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dst := range []string{"bar", "baz"} {
+		if err := fs.Copy("foo", dst); err != nil {
+			t.Fatalf("copy(foo, %q) = %v", dst, err)
+		}
+		if b, err := fs.ReadFile(dst); err != nil || string(b) != "hello" {
+			t.Fatalf("readfile(%q) = %q, %v, want %q", dst, b, err, "hello")
+		}
+		if st, err := fs.Stat(dst); err != nil || st.Mode() != 0600 {
+			t.Fatalf("stat(%q) = %v, %v, want mode %v", dst, st, err, os.FileMode(0600))
+		}
+	}
+	f, err := fs.OpenFile("baz", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("jelly")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fs.ReadFile("foo"); err != nil || string(b) != "hello" {
+		t.Fatalf("readfile(foo) = %q, %v, want %q", b, err, "hello")
+	}
+	if err := fs.Copy("missing", "qux"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("copy(missing, qux) = %v, want %v", err, os.ErrNotExist)
+	}
+	if fs.Exists("qux") {
+		t.Fatalf("exists(qux) = true after failed copy, want false")
+	}
+}
+
+func TestCopySelf(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Link("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	for _, dst := range []string{"foo", "./foo", "bar"} {
+		if err := fs.Copy("foo", dst); !errors.Is(err, os.ErrInvalid) {
+			t.Fatalf("copy(foo, %q) = %v, want %v", dst, err, os.ErrInvalid)
+		}
+		if b, err := fs.ReadFile("foo"); err != nil || string(b) != "hello" {
+			t.Fatalf("readfile(foo) = %q, %v after copy(foo, %q), want %q", b, err, dst, "hello")
+		}
+	}
+}
+
+func TestChtimes(t *testing.T) {
+	fs := New()
+	if _, err := fs.Create("foo"); err != nil {
+		t.Fatal(err)
+	}
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mtime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := fs.Chtimes("foo", atime, mtime); err != nil {
+		t.Fatalf("chtimes(foo) = %v", err)
+	}
+	st, err := fs.Stat("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := st.ModTime(); !got.Equal(mtime) {
+		t.Fatalf("mtime = %v, want %v", got, mtime)
+	}
+	if got := st.Sys().(*SysInfo).AccessTime; !got.Equal(atime) {
+		t.Fatalf("atime = %v, want %v", got, atime)
+	}
+	if err := fs.Chtimes("missing", atime, mtime); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("chtimes(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestChtimesNanoseconds(t *testing.T) {
+	fs := New()
+	if _, err := fs.Create("foo"); err != nil {
+		t.Fatal(err)
+	}
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	mtime := time.Date(2021, 1, 2, 3, 4, 5, 987654321, time.UTC)
+	if err := fs.Chtimes("foo", atime, mtime); err != nil {
+		t.Fatalf("chtimes(foo) = %v", err)
+	}
+	st, err := fs.Stat("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := st.ModTime(); got != mtime {
+		t.Fatalf("mtime = %v, want %v", got, mtime)
+	}
+	if got := st.Sys().(*SysInfo).AccessTime; got != atime {
+		t.Fatalf("atime = %v, want %v", got, atime)
+	}
+}
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestClock(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)}
+	fs := New()
+	fs.SetClock(clock)
+	fd, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, _ := fd.Stat()
+	if got := st.ModTime(); !got.Equal(clock.now) {
+		t.Fatalf("mtime = %v, want %v", got, clock.now)
+	}
+	clock.now = clock.now.Add(time.Hour)
+	if _, err := fd.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	st, _ = fd.Stat()
+	if got := st.ModTime(); !got.Equal(clock.now) {
+		t.Fatalf("mtime = %v, want %v", got, clock.now)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/b", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo.json", "bar.json", "baz.txt", "a/qux.json", "a/b/quux.json"} {
+		if _, err := fs.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{pattern: "*", want: []string{"a", "bar.json", "baz.txt", "foo.json"}},
+		{pattern: "*.json", want: []string{"bar.json", "foo.json"}},
+		{pattern: "**.json", want: []string{"bar.json", "foo.json"}},
+		{pattern: "*/*.json", want: []string{"a/qux.json"}},
+		{pattern: "a/*/*", want: []string{"a/b/quux.json"}},
+		{pattern: "nothing", want: nil},
+	}
+	for _, tc := range tests {
+		got, err := fs.Glob(tc.pattern)
+		if err != nil {
+			t.Fatalf("glob(%q) = %v", tc.pattern, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("glob(%q) = %q, want %q", tc.pattern, got, tc.want)
+		}
+	}
+	if _, err := fs.Glob("[x"); err != path.ErrBadPattern {
+		t.Fatalf("glob([x) = %v, want %v", err, path.ErrBadPattern)
+	}
+}
+
+func TestDirEntry(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("dir/foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("dir/foo", "link"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]iofs.FileMode{
+		"dir":     iofs.ModeDir,
+		"dir/foo": 0,
+		"link":    iofs.ModeSymlink,
+	}
+	err := iofs.WalkDir(fs, ".", func(p string, d iofs.DirEntry, err error) error {
+		if err != nil || p == "." {
+			return err
+		}
+		if _, ok := d.(*DirEntry); !ok {
+			t.Errorf("walkdir(%s) entry = %T, want *DirEntry", p, d)
+		}
+		if d.Type() != want[p] || d.IsDir() != (want[p] == iofs.ModeDir) {
+			t.Errorf("walkdir(%s) type = %v, want %v", p, d.Type(), want[p])
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		lst, err := fs.Lstat(p)
+		if err != nil {
+			return err
+		}
+		if info.Name() != lst.Name() || info.Mode() != lst.Mode() || info.Size() != lst.Size() {
+			t.Errorf("walkdir(%s) info = %v %v %v, want %v %v %v", p, info.Name(), info.Mode(), info.Size(), lst.Name(), lst.Mode(), lst.Size())
+		}
+		delete(want, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkdir() = %v", err)
+	}
+	if len(want) != 0 {
+		t.Fatalf("walkdir() missed %v", want)
+	}
+	// Info reports the current state of the file.
+	entries, err := fs.ReadDir("dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("dir/foo", []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := entries[0].Info(); info.Size() != 11 {
+		t.Fatalf("info().Size() = %d, want %d", info.Size(), 11)
+	}
+	if got, want := fmt.Sprint(entries[0]), "- foo"; got != want {
+		t.Fatalf("entry = %q, want %q", got, want)
+	}
+}
+
+func TestSortedListings(t *testing.T) {
+	names := []string{"b", "a", "d", "c", "e", "aa", "B", "_"}
+	want := []string{"B", "_", "a", "aa", "b", "c", "d", "e"}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		fs := New()
+		if err := fs.Mkdir("dir", 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, j := range r.Perm(len(names)) {
+			if err := fs.WriteFile("dir/"+names[j], nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		entries, err := fs.ReadDir("dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Name())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("readdir(dir) = %q, want %q", got, want)
+		}
+		f, err := fs.Open("dir")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err = f.(*File).Readdirnames(-1)
+		f.Close()
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("readdirnames(-1) = %q, %v, want %q", got, err, want)
+		}
+		got, err = fs.Glob("dir/*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range got {
+			got[i] = path.Base(got[i])
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("glob(dir/*) = %q, want %q", got, want)
+		}
+		got = nil
+		err = fs.WalkDir("dir", func(p string, d iofs.DirEntry, err error) error {
+			if p != "dir" {
+				got = append(got, d.Name())
+			}
+			return err
+		})
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Fatalf("walkdir(dir) = %q, %v, want %q", got, err, want)
+		}
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/b", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Mkdir("c", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "a/bar", "a/b/baz", "c/qux"} {
+		if _, err := fs.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	err := fs.WalkDir(".", func(p string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		got = append(got, p)
+		if p == "a/b" {
+			return iofs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkdir() = %v", err)
+	}
+	want := []string{".", "a", "a/b", "a/bar", "c", "c/qux", "foo"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("walkdir() = %q, want %q", got, want)
+	}
+	errStop := errors.New("stop")
+	err = fs.WalkDir("a", func(p string, d iofs.DirEntry, err error) error {
+		return errStop
+	})
+	if err != errStop {
+		t.Fatalf("walkdir() = %v, want %v", err, errStop)
+	}
+}
+
+func TestClone(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("a/foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := fs.Clone()
+	if err := c.WriteFile("a/foo", []byte("clone"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("bar", []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := fs.ReadFile("a/foo"); string(b) != "hello" {
+		t.Fatalf("original readfile(a/foo) = %q, want %q", b, "hello")
+	}
+	if b, _ := c.ReadFile("a/foo"); string(b) != "clone" {
+		t.Fatalf("clone readfile(a/foo) = %q, want %q", b, "clone")
+	}
+	if _, err := c.Stat("bar"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("clone stat(bar) = %v, want %v", err, os.ErrNotExist)
+	}
+	if st, err := c.Stat("a"); err != nil || !st.IsDir() {
+		t.Fatalf("clone stat(a) = %v, %v, want directory", st, err)
+	}
+}
+
+func TestCloneQuota(t *testing.T) {
+	fs := New()
+	fs.SetQuota(10)
+	if err := fs.WriteFile("foo", []byte("hi"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Link("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("tmp", []byte("123456"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := fs.Open("tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	if err := fs.Remove("tmp"); err != nil {
+		t.Fatal(err)
+	}
+	// Only foo is copied, and only once for both of its names.
+	c := fs.Clone()
+	if err := c.WriteFile("baz", []byte("12345678"), 0644); err != nil {
+		t.Fatalf("clone writefile(baz) = %v, want nil", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	fs := New()
+	for _, name := range []string{"foo", "bar"} {
+		if err := fs.WriteFile(name, []byte("hello "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := fs.Snapshot()
+	if &s.files["foo"].Data.Bytes()[0] != &fs.files["foo"].Data.Bytes()[0] {
+		t.Fatalf("snapshot does not share data of unmodified file")
+	}
+	fd, err := s.OpenFile("foo", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("J")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := fs.ReadFile("foo"); string(b) != "hello foo" {
+		t.Fatalf("original readfile(foo) = %q, want %q", b, "hello foo")
+	}
+	if b, _ := s.ReadFile("foo"); string(b) != "Jello foo" {
+		t.Fatalf("snapshot readfile(foo) = %q, want %q", b, "Jello foo")
+	}
+	if err := fs.Truncate("bar", 2); err != nil {
+		t.Fatal(err)
+	}
+	fd, err = fs.OpenFile("bar", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("XXX")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := s.ReadFile("bar"); string(b) != "hello bar" {
+		t.Fatalf("snapshot readfile(bar) = %q, want %q", b, "hello bar")
+	}
+	if b, _ := fs.ReadFile("bar"); string(b) != "heXXX" {
+		t.Fatalf("original readfile(bar) = %q, want %q", b, "heXXX")
+	}
+}
+
+func TestQuota(t *testing.T) {
+	fs := New()
+	fs.SetQuota(10)
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := fs.Create("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("world!")); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("write(world!) = %v, want %v", err, syscall.ENOSPC)
+	}
+	if _, err := fd.Write([]byte("world")); err != nil {
+		t.Fatalf("write(world) = %v", err)
+	}
+	if err := fd.Truncate(6); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("truncate(6) = %v, want %v", err, syscall.ENOSPC)
+	}
+	if _, err := fd.WriteAt([]byte("x"), 0); err != nil {
+		t.Fatalf("writeat(0) = %v", err)
+	}
+	if _, err := fd.ReadFrom(strings.NewReader("x")); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("readfrom(x) = %v, want %v", err, syscall.ENOSPC)
+	}
+	if err := fs.Remove("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.ReadFrom(strings.NewReader("01234")); err != nil {
+		t.Fatalf("readfrom(01234) = %v", err)
+	}
+	if err := fs.Truncate("bar", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("foo", []byte("0123456789"), 0644); err != nil {
+		t.Fatalf("writefile(foo) = %v", err)
+	}
+}
+
+func TestMaxFileSize(t *testing.T) {
+	fs := New()
+	fs.SetMaxFileSize(5)
+	fd, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("hell")); err != nil {
+		t.Fatalf("write(hell) = %v", err)
+	}
+	if _, err := fd.Write([]byte("o!")); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("write(o!) = %v, want %v", err, syscall.ENOSPC)
+	}
+	if _, err := fd.Write([]byte("o")); err != nil {
+		t.Fatalf("write(o) = %v", err)
+	}
+	if _, err := fd.ReadFrom(strings.NewReader("!")); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("readfrom(!) = %v, want %v", err, syscall.ENOSPC)
+	}
+	if err := fs.WriteFile("bar", []byte("world"), 0644); err != nil {
+		t.Fatalf("writefile(bar) = %v", err)
+	}
+	fd, err = fs.Create("baz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.ReadFrom(strings.NewReader("123456")); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("readfrom(123456) = %v, want %v", err, syscall.ENOSPC)
+	}
+	if n, err := fd.ReadFrom(strings.NewReader("12345")); n != 5 || err != nil {
+		t.Fatalf("readfrom(12345) = %d, %v, want 5, nil", n, err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	fs := New()
+	if files, bytes := fs.Usage(); files != 0 || bytes != 0 {
+		t.Fatalf("usage() = %d, %d, want 0, 0", files, bytes)
+	}
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "a/bar", "a/baz"} {
+		if err := fs.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if files, bytes := fs.Usage(); files != 4 || bytes != 13 {
+		t.Fatalf("usage() = %d, %d, want 4, 13", files, bytes)
+	}
+	if err := fs.Remove("a/baz"); err != nil {
+		t.Fatal(err)
+	}
+	if files, bytes := fs.Usage(); files != 3 || bytes != 8 {
+		t.Fatalf("usage() = %d, %d, want 3, 8", files, bytes)
+	}
+}
+
+func TestRange(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "a/bar", "a/baz"} {
+		if err := fs.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []string
+	fs.Range(func(name string, info os.FileInfo) bool {
+		if info.Name() != path.Base(name) {
+			t.Errorf("range(%q) name = %q, want %q", name, info.Name(), path.Base(name))
+		}
+		got = append(got, name)
+		return true
+	})
+	if want := []string{"a", "a/bar", "a/baz", "foo"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("range() = %q, want %q", got, want)
+	}
+	got = nil
+	fs.Range(func(name string, info os.FileInfo) bool {
+		got = append(got, name)
+		return len(got) < 2
+	})
+	if want := []string{"a", "a/bar"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("range() stopping early = %q, want %q", got, want)
+	}
+}
+
+func TestRangeConcurrent(t *testing.T) {
+	fs := New()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			fs.WriteFile(fmt.Sprintf("f%d", i), []byte("x"), 0644)
+			fs.Remove(fmt.Sprintf("f%d", i/2))
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		fs.Range(func(name string, info os.FileInfo) bool {
+			fs.Stat(name)
+			return true
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	fs := New()
+	fs.SetQuota(10)
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "a/bar"} {
+		if err := fs.WriteFile(name, []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, stop := fs.Watch()
+	defer stop()
+	fs.Reset()
+	if files, bytes := fs.Usage(); files != 0 || bytes != 0 {
+		t.Fatalf("usage() = %d, %d after reset, want 0, 0", files, bytes)
+	}
+	want := []Event{{"foo", Remove}, {"a/bar", Remove}, {"a", Remove}}
+	for _, w := range want {
+		if got := <-events; got != w {
+			t.Fatalf("event = %v, want %v", got, w)
+		}
+	}
+	if _, err := fs.Stat("a"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("stat(a) = %v after reset, want %v", err, os.ErrNotExist)
+	}
+	// The quota is kept and all of it is available again.
+	if err := fs.WriteFile("baz", make([]byte, 10), 0644); err != nil {
+		t.Fatalf("writefile(baz) = %v after reset, want nil", err)
+	}
+	if err := fs.WriteFile("qux", []byte("x"), 0644); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("writefile(qux) = %v, want %v", err, syscall.ENOSPC)
+	}
+}
+
+func TestRemoveOpen(t *testing.T) {
+	fs := New()
+	fs.SetQuota(10)
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.OpenFile("foo", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Open("foo"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("open(foo) = %v, want %v", err, os.ErrNotExist)
+	}
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("!")); err != nil {
+		t.Fatalf("write() = %v after remove, want nil", err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "hello!" {
+		t.Fatalf("read() = %q after remove, want %q", b, "hello!")
+	}
+	// The open handle still holds its data.
+	if err := fs.WriteFile("bar", []byte("12345"), 0644); err == nil {
+		t.Fatalf("writefile(bar) = nil, want error")
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("bar", []byte("12345"), 0644); err != nil {
+		t.Fatalf("writefile(bar) = %v after close, want nil", err)
+	}
+}
+
+func TestReadFileFS(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var fsys iofs.FS = fs
+	if _, ok := fsys.(iofs.ReadFileFS); !ok {
+		t.Fatalf("%T does not implement fs.ReadFileFS", fsys)
+	}
+	b, err := iofs.ReadFile(fsys, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The result must be a copy.
+	b[0] = 'j'
+	if b, err := iofs.ReadFile(fsys, "foo"); err != nil || string(b) != "hello" {
+		t.Fatalf("readfile(foo) = %q, %v, want %q", b, err, "hello")
+	}
+	var perr *os.PathError
+	if _, err := iofs.ReadFile(fsys, "missing"); !errors.As(err, &perr) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("readfile(missing) = %v, want *PathError for %v", err, os.ErrNotExist)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				b, err := iofs.ReadFile(fsys, "foo")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if s := string(b); s != "hello" && s != "world" {
+					t.Errorf("readfile(foo) = %q, want %q or %q", s, "hello", "world")
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			f, err := fs.OpenFile("foo", os.O_WRONLY, 0)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer f.Close()
+			for j := 0; j < 100; j++ {
+				if _, err := f.WriteAt([]byte("world"), 0); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,11 @@
+package ramfs
+
+import (
+	"net/http"
+)
+
+// HTTPDir returns an http.FileSystem serving the contents of the
+// filesystem, suitable for use with http.FileServer.
+func (fs *Filesystem) HTTPDir() http.FileSystem {
+	return http.FS(fs)
+}
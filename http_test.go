@@ -0,0 +1,49 @@
+package ramfs
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPDir(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("a/foo.txt", []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.FileServer(fs.HTTPDir()))
+	defer srv.Close()
+
+	get := func(p string) (*http.Response, string) {
+		resp, err := http.Get(srv.URL + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, string(b)
+	}
+	resp, body := get("/a/foo.txt")
+	if resp.StatusCode != http.StatusOK || body != "hello world" {
+		t.Fatalf("GET /a/foo.txt = %d %q, want %d %q", resp.StatusCode, body, http.StatusOK, "hello world")
+	}
+	if resp.ContentLength != int64(len(body)) {
+		t.Fatalf("GET /a/foo.txt Content-Length = %d, want %d", resp.ContentLength, len(body))
+	}
+	resp, body = get("/a/")
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `href="foo.txt"`) {
+		t.Fatalf("GET /a/ = %d %q, want listing of foo.txt", resp.StatusCode, body)
+	}
+	resp, _ = get("/missing")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET /missing = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
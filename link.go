@@ -0,0 +1,90 @@
+package ramfs
+
+import (
+	"os"
+	"path"
+	"syscall"
+)
+
+// Link creates newname as a hard link to the oldname file. Both names
+// refer to the same data afterwards; the data is kept until the last name
+// is removed.
+// If there is an error, it will be of type *LinkError.
+func (fs *Filesystem) Link(oldname, newname string) error {
+	oldclean, ok := cleanPath(oldname)
+	newclean, newok := cleanPath(newname)
+	if !ok || !newok {
+		return &os.LinkError{
+			Op:  "link",
+			Old: oldname,
+			New: newname,
+			Err: os.ErrInvalid,
+		}
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	oldresolved, err := fs.resolveParent(oldclean)
+	if err != nil {
+		return &os.LinkError{
+			Op:  "link",
+			Old: oldname,
+			New: newname,
+			Err: err,
+		}
+	}
+	f, ok := fs.files[oldresolved]
+	if !ok {
+		return &os.LinkError{
+			Op:  "link",
+			Old: oldname,
+			New: newname,
+			Err: os.ErrNotExist,
+		}
+	}
+	if f.IsDir {
+		return &os.LinkError{
+			Op:  "link",
+			Old: oldname,
+			New: newname,
+			Err: syscall.EPERM,
+		}
+	}
+	name, err := fs.resolveParent(newclean)
+	if err != nil {
+		return &os.LinkError{
+			Op:  "link",
+			Old: oldname,
+			New: newname,
+			Err: err,
+		}
+	}
+	if _, ok := fs.node(name); ok {
+		return &os.LinkError{
+			Op:  "link",
+			Old: oldname,
+			New: newname,
+			Err: os.ErrExist,
+		}
+	}
+	parent, ok := fs.node(path.Dir(name))
+	if !ok {
+		return &os.LinkError{
+			Op:  "link",
+			Old: oldname,
+			New: newname,
+			Err: os.ErrNotExist,
+		}
+	}
+	if !parent.IsDir {
+		return &os.LinkError{
+			Op:  "link",
+			Old: oldname,
+			New: newname,
+			Err: syscall.ENOTDIR,
+		}
+	}
+	f.nlink.Add(1)
+	fs.put(name, f)
+	fs.emit(name, Create)
+	return nil
+}
@@ -0,0 +1,118 @@
+package ramfs
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestLink(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Link("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.OpenFile("bar", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte(" world")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "bar"} {
+		b, err := fs.ReadFile(name)
+		if err != nil {
+			t.Fatalf("readfile(%q) = %v", name, err)
+		}
+		if string(b) != "hello world" {
+			t.Fatalf("readfile(%q) = %q, want %q", name, b, "hello world")
+		}
+		st, err := fs.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Name() != name {
+			t.Fatalf("stat(%q).Name() = %q, want %q", name, st.Name(), name)
+		}
+	}
+	if err := fs.Remove("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fs.ReadFile("bar"); err != nil || string(b) != "hello world" {
+		t.Fatalf("readfile(bar) = %q, %v, want %q", b, err, "hello world")
+	}
+	if files, bytes := fs.Usage(); files != 1 || bytes != 11 {
+		t.Fatalf("usage() = %v, %v, want %v, %v", files, bytes, 1, 11)
+	}
+	if err := fs.Link("bar", "bar"); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("link(bar, bar) = %v, want %v", err, os.ErrExist)
+	}
+	if err := fs.Link("missing", "baz"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("link(missing, baz) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestLinkQuota(t *testing.T) {
+	fs := New()
+	fs.SetQuota(10)
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Link("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("foo"); err != nil {
+		t.Fatal(err)
+	}
+	// The data is still referenced by bar, so it still counts.
+	if err := fs.WriteFile("baz", []byte("123456"), 0644); err == nil {
+		t.Fatalf("writefile(baz) = nil, want error")
+	}
+	if err := fs.Remove("bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("baz", []byte("123456"), 0644); err != nil {
+		t.Fatalf("writefile(baz) = %v, want nil", err)
+	}
+}
+
+func TestLinkInode(t *testing.T) {
+	fs := New()
+	for _, name := range []string{"foo", "baz"} {
+		if err := fs.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Link("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	sys := make(map[string]*SysInfo)
+	for _, name := range []string{"foo", "bar", "baz"} {
+		st, err := fs.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sys[name] = st.Sys().(*SysInfo)
+	}
+	if sys["foo"].Ino != sys["bar"].Ino {
+		t.Fatalf("ino(foo) = %d, ino(bar) = %d, want equal", sys["foo"].Ino, sys["bar"].Ino)
+	}
+	if sys["foo"].Ino == sys["baz"].Ino {
+		t.Fatalf("ino(foo) = ino(baz) = %d, want different", sys["foo"].Ino)
+	}
+	if sys["bar"].Nlink != 2 || sys["baz"].Nlink != 1 {
+		t.Fatalf("nlink(bar), nlink(baz) = %d, %d, want %d, %d", sys["bar"].Nlink, sys["baz"].Nlink, 2, 1)
+	}
+	if err := fs.Remove("foo"); err != nil {
+		t.Fatal(err)
+	}
+	st, err := fs.Stat("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := st.Sys().(*SysInfo); got.Nlink != 1 || got.Ino != sys["bar"].Ino {
+		t.Fatalf("sys(bar) = %+v after remove, want nlink %d and ino %d", got, 1, sys["bar"].Ino)
+	}
+}
@@ -0,0 +1,97 @@
+package ramfs
+
+import (
+	"context"
+	"os"
+	"syscall"
+)
+
+// Lock places an advisory exclusive lock on the file, blocking until the
+// lock is available. Like flock(2), the lock belongs to the File rather
+// than the goroutine; locking a File that already holds the lock does
+// nothing. Locks are only advisory: they do not prevent reading or
+// writing the file. If the file was opened with OpenContext, Lock gives
+// up waiting once the context is done and returns its error.
+// If there is an error, it will be of type *PathError.
+func (f *File) Lock() error {
+	if err := f.checkValid("lock"); err != nil {
+		return err
+	}
+	ctx := f.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := f.node.lock(ctx, f, true); err != nil {
+		return &os.PathError{
+			Op:   "lock",
+			Path: f.Name(),
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// TryLock is like Lock but does not block. If another File holds the
+// lock, it fails with EWOULDBLOCK.
+// If there is an error, it will be of type *PathError.
+func (f *File) TryLock() error {
+	if err := f.checkValid("trylock"); err != nil {
+		return err
+	}
+	if err := f.node.lock(context.Background(), f, false); err != nil {
+		return &os.PathError{
+			Op:   "trylock",
+			Path: f.Name(),
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// Unlock releases the advisory lock held by the file. Unlocking a File
+// that does not hold the lock does nothing.
+// If there is an error, it will be of type *PathError.
+func (f *File) Unlock() error {
+	if err := f.checkValid("unlock"); err != nil {
+		return err
+	}
+	n := f.node
+	n.lockMu.Lock()
+	defer n.lockMu.Unlock()
+	if n.locker != f {
+		return nil
+	}
+	n.locker = nil
+	if n.unlocked != nil {
+		close(n.unlocked)
+		n.unlocked = nil
+	}
+	return nil
+}
+
+// lock acquires the advisory lock of the node for f. If wait is false, it
+// fails with EWOULDBLOCK instead of waiting for the lock. Waiting is
+// aborted once ctx is done.
+func (n *Node) lock(ctx context.Context, f *File, wait bool) error {
+	n.lockMu.Lock()
+	defer n.lockMu.Unlock()
+	for n.locker != nil && n.locker != f {
+		if !wait {
+			return syscall.EWOULDBLOCK
+		}
+		if n.unlocked == nil {
+			n.unlocked = make(chan struct{})
+		}
+		c := n.unlocked
+		n.lockMu.Unlock()
+		select {
+		case <-c:
+		case <-ctx.Done():
+			n.lockMu.Lock()
+			return ctx.Err()
+		}
+		n.lockMu.Lock()
+	}
+	n.locker = f
+	return nil
+}
@@ -0,0 +1,123 @@
+package ramfs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestLock(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fd1, err := fs.OpenFile("foo", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd2, err := fs.OpenFile("foo", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fd1.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd1.TryLock(); err != nil {
+		t.Fatalf("trylock() = %v on held lock, want nil", err)
+	}
+	if err := fd2.TryLock(); !errors.Is(err, syscall.EWOULDBLOCK) {
+		t.Fatalf("trylock() = %v, want %v", err, syscall.EWOULDBLOCK)
+	}
+	if err := fd1.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd2.TryLock(); err != nil {
+		t.Fatalf("trylock() = %v, want nil", err)
+	}
+	if err := fd2.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLockContention(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("counter", []byte{0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	const n = 100
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		fd, err := fs.OpenFile("counter", os.O_RDWR, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b := make([]byte, 1)
+			for j := 0; j < n; j++ {
+				if err := fd.Lock(); err != nil {
+					t.Error(err)
+					return
+				}
+				// Read, modify and write back; without the lock
+				// increments would get lost.
+				if _, err := fd.ReadAt(b, 0); err != nil {
+					t.Error(err)
+				}
+				b[0]++
+				if _, err := fd.WriteAt(b, 0); err != nil {
+					t.Error(err)
+				}
+				if err := fd.Unlock(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	b, err := fs.ReadFile("counter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != 2*n {
+		t.Fatalf("counter = %d, want %d", b[0], 2*n)
+	}
+}
+
+func TestLockContext(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fd1, err := fs.OpenFile("foo", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fd1.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	fd2, err := fs.OpenContext(ctx, "foo", os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- fd2.Lock()
+	}()
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("lock() = %v, want %v", err, context.Canceled)
+	}
+	if err := fd2.TryLock(); !errors.Is(err, syscall.EWOULDBLOCK) {
+		t.Fatalf("trylock() = %v, want %v", err, syscall.EWOULDBLOCK)
+	}
+	if _, err := fs.OpenContext(ctx, "foo", os.O_RDWR, 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("opencontext() = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,46 @@
+package ramfs
+
+// Observer is notified about operations on a filesystem, for example to
+// collect metrics or write an audit log. Its methods are called
+// synchronously after the operation, possibly while locks of the
+// filesystem are held, so they must be quick and must not call back into
+// the filesystem.
+type Observer interface {
+	// OnOpen is called after name has been opened.
+	OnOpen(name string)
+	// OnRead is called after n bytes have been read from name.
+	OnRead(name string, n int)
+	// OnWrite is called after n bytes have been written to name.
+	OnWrite(name string, n int)
+	// OnRemove is called after name has been removed.
+	OnRemove(name string)
+}
+
+// SetObserver sets the Observer notified about operations on the
+// filesystem. A nil Observer disables notifications. It must be called
+// before the filesystem is used.
+func (fs *Filesystem) SetObserver(o Observer) {
+	fs.observer = o
+}
+
+// observer returns the Observer of the filesystem of the node, or nil.
+func (n *Node) observer() Observer {
+	if n.fs == nil {
+		return nil
+	}
+	return n.fs.observer
+}
+
+// observeRead reports a read of n bytes from f to the Observer, if any.
+func (f *File) observeRead(n int) {
+	if o := f.node.observer(); o != nil {
+		o.OnRead(f.Name(), n)
+	}
+}
+
+// observeWrite reports a write of n bytes to f to the Observer, if any.
+func (f *File) observeWrite(n int) {
+	if o := f.node.observer(); o != nil {
+		o.OnWrite(f.Name(), n)
+	}
+}
@@ -0,0 +1,72 @@
+package ramfs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+)
+
+type recorder struct {
+	calls []string
+}
+
+func (r *recorder) OnOpen(name string) {
+	r.calls = append(r.calls, fmt.Sprintf("open %s", name))
+}
+
+func (r *recorder) OnRead(name string, n int) {
+	r.calls = append(r.calls, fmt.Sprintf("read %s %d", name, n))
+}
+
+func (r *recorder) OnWrite(name string, n int) {
+	r.calls = append(r.calls, fmt.Sprintf("write %s %d", name, n))
+}
+
+func (r *recorder) OnRemove(name string) {
+	r.calls = append(r.calls, fmt.Sprintf("remove %s", name))
+}
+
+func TestObserver(t *testing.T) {
+	fs := New()
+	r := &recorder{}
+	fs.SetObserver(r)
+	f, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Read(make([]byte, 3)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.ReadFile("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("foo"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"open foo",
+		"write foo 5",
+		"read foo 3",
+		"open foo",
+		"read foo 5",
+		"remove foo",
+	}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Fatalf("calls = %q, want %q", r.calls, want)
+	}
+	fs.SetObserver(nil)
+	if _, err := fs.OpenFile("bar", os.O_RDWR|os.O_CREATE, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.calls) != len(want) {
+		t.Fatalf("calls = %q after SetObserver(nil), want %q", r.calls, want)
+	}
+}
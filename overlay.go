@@ -0,0 +1,286 @@
+package ramfs
+
+import (
+	"errors"
+	iofs "io/fs"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"syscall"
+)
+
+// OverlayFS is a union of two filesystems. Reads are served from the
+// upper layer and fall back to the lower layer, while all modifications
+// land in the upper layer. A file of the lower layer is copied up before
+// it is modified, and removing it records a whiteout that masks it.
+// The lower layer is never modified.
+type OverlayFS struct {
+	upper, lower *Filesystem
+
+	mu       sync.Mutex
+	whiteout map[string]bool
+}
+
+// Overlay returns an overlay of upper on top of lower.
+func Overlay(upper, lower *Filesystem) *OverlayFS {
+	return &OverlayFS{
+		upper:    upper,
+		lower:    lower,
+		whiteout: make(map[string]bool),
+	}
+}
+
+// Open opens the named file for reading. Directory handles only list the
+// entries of the topmost layer containing the directory; use ReadDir for
+// a merged listing.
+func (o *OverlayFS) Open(name string) (iofs.File, error) {
+	if !iofs.ValidPath(name) {
+		return nil, &os.PathError{
+			Op:   "open",
+			Err:  os.ErrInvalid,
+			Path: name,
+		}
+	}
+	return o.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile opens the named file. Opening a file of the lower layer for
+// writing copies it to the upper layer first.
+func (o *OverlayFS) OpenFile(name string, flag int, perm os.FileMode) (*File, error) {
+	name, err := clean("open", name)
+	if err != nil {
+		return nil, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		if o.whiteout[name] {
+			return nil, &os.PathError{
+				Op:   "open",
+				Err:  os.ErrNotExist,
+				Path: name,
+			}
+		}
+		f, err := o.upper.OpenFile(name, flag, perm)
+		if errors.Is(err, os.ErrNotExist) {
+			return o.lower.OpenFile(name, flag, perm)
+		}
+		return f, err
+	}
+	if err := o.copyUp(path.Dir(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if !o.whiteout[name] {
+		if err := o.copyUp(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	f, err := o.upper.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	delete(o.whiteout, name)
+	return f, nil
+}
+
+// Create creates or truncates the named file in the upper layer.
+func (o *OverlayFS) Create(name string) (*File, error) {
+	return o.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// WriteFile writes data to the named file in the upper layer.
+func (o *OverlayFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	f, err := o.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(data)
+	return err
+}
+
+// ReadFile reads the named file from the topmost layer containing it.
+func (o *OverlayFS) ReadFile(name string) ([]byte, error) {
+	name, err := clean("read", name)
+	if err != nil {
+		return nil, err
+	}
+	o.mu.Lock()
+	fs, err := o.layer("read", name)
+	o.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadFile(name)
+}
+
+// Stat returns a FileInfo describing the named file in the topmost layer
+// containing it.
+func (o *OverlayFS) Stat(name string) (os.FileInfo, error) {
+	name, err := clean("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	o.mu.Lock()
+	fs, err := o.layer("stat", name)
+	o.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(name)
+}
+
+// Mkdir creates a new directory in the upper layer.
+func (o *OverlayFS) Mkdir(name string, perm os.FileMode) error {
+	name, err := clean("mkdir", name)
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, err := o.layer("mkdir", name); err == nil {
+		return &os.PathError{
+			Op:   "mkdir",
+			Err:  os.ErrExist,
+			Path: name,
+		}
+	}
+	if err := o.copyUp(path.Dir(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := o.upper.Mkdir(name, perm); err != nil {
+		return err
+	}
+	delete(o.whiteout, name)
+	return nil
+}
+
+// Remove removes the named file or empty directory. Files of the lower
+// layer are masked by a whiteout.
+func (o *OverlayFS) Remove(name string) error {
+	name, err := clean("remove", name)
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, err := o.layer("remove", name); err != nil {
+		return err
+	}
+	if len(o.readDir(name)) > 0 {
+		return &os.PathError{
+			Op:   "remove",
+			Err:  syscall.ENOTEMPTY,
+			Path: name,
+		}
+	}
+	if _, err := o.upper.Stat(name); err == nil {
+		if err := o.upper.Remove(name); err != nil {
+			return err
+		}
+	}
+	if _, err := o.lower.Stat(name); err == nil {
+		o.whiteout[name] = true
+	}
+	return nil
+}
+
+// ReadDir reads the named directory and returns the merged entries of
+// both layers sorted by filename.
+func (o *OverlayFS) ReadDir(name string) ([]iofs.DirEntry, error) {
+	name, err := clean("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	fs, err := o.layer("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	st, err := fs.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !st.IsDir() {
+		return nil, &os.PathError{
+			Op:   "readdir",
+			Err:  syscall.ENOTDIR,
+			Path: name,
+		}
+	}
+	return o.readDir(name), nil
+}
+
+// readDir returns the merged entries of the directory name. The caller
+// must hold o.mu.
+func (o *OverlayFS) readDir(name string) []iofs.DirEntry {
+	seen := make(map[string]bool)
+	var entries []iofs.DirEntry
+	for _, fs := range []*Filesystem{o.upper, o.lower} {
+		list, _ := fs.ReadDir(name)
+		for _, e := range list {
+			if seen[e.Name()] || o.whiteout[path.Join(name, e.Name())] {
+				continue
+			}
+			seen[e.Name()] = true
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries
+}
+
+// layer returns the topmost layer containing name. The caller must hold
+// o.mu.
+func (o *OverlayFS) layer(op, name string) (*Filesystem, error) {
+	if !o.whiteout[name] {
+		if _, err := o.upper.Stat(name); err == nil {
+			return o.upper, nil
+		}
+		if _, err := o.lower.Stat(name); err == nil {
+			return o.lower, nil
+		}
+	}
+	return nil, &os.PathError{
+		Op:   op,
+		Err:  os.ErrNotExist,
+		Path: name,
+	}
+}
+
+// copyUp copies name and its parent directories from the lower to the
+// upper layer unless the upper layer already contains it. The caller must
+// hold o.mu.
+func (o *OverlayFS) copyUp(name string) error {
+	if _, err := o.upper.Stat(name); err == nil {
+		return nil
+	}
+	st, err := o.lower.Stat(name)
+	if err != nil {
+		return err
+	}
+	if err := o.copyUp(path.Dir(name)); err != nil {
+		return err
+	}
+	if st.IsDir() {
+		if err := o.upper.Mkdir(name, st.Mode().Perm()); err != nil {
+			return err
+		}
+	} else {
+		data, err := o.lower.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		if err := o.upper.WriteFile(name, data, st.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	if err := o.upper.Chmod(name, st.Mode()); err != nil {
+		return err
+	}
+	return o.upper.Chtimes(name, st.Sys().(*SysInfo).AccessTime, st.ModTime())
+}
@@ -0,0 +1,124 @@
+package ramfs
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestOverlay(t *testing.T) {
+	lower := New()
+	if err := lower.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "a/bar", "a/baz"} {
+		if err := lower.WriteFile(name, []byte("lower "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	upper := New()
+	o := Overlay(upper, lower)
+
+	// Reads fall through to the lower layer.
+	if b, err := o.ReadFile("a/bar"); err != nil || string(b) != "lower a/bar" {
+		t.Fatalf("readfile(a/bar) = %q, %v, want %q", b, err, "lower a/bar")
+	}
+
+	// Writes copy the file up.
+	fd, err := o.OpenFile("a/bar", os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("open(a/bar) = %v", err)
+	}
+	if _, err := fd.Write([]byte("!")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := o.ReadFile("a/bar"); string(b) != "lower a/bar!" {
+		t.Fatalf("readfile(a/bar) = %q, want %q", b, "lower a/bar!")
+	}
+	if b, _ := upper.ReadFile("a/bar"); string(b) != "lower a/bar!" {
+		t.Fatalf("upper readfile(a/bar) = %q, want %q", b, "lower a/bar!")
+	}
+	if b, _ := lower.ReadFile("a/bar"); string(b) != "lower a/bar" {
+		t.Fatalf("lower readfile(a/bar) = %q, want %q", b, "lower a/bar")
+	}
+
+	// Removing a lower file masks it.
+	if err := o.Remove("foo"); err != nil {
+		t.Fatalf("remove(foo) = %v", err)
+	}
+	if _, err := o.Stat("foo"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("stat(foo) = %v, want %v", err, os.ErrNotExist)
+	}
+	if _, err := lower.Stat("foo"); err != nil {
+		t.Fatalf("lower stat(foo) = %v", err)
+	}
+	if err := o.Remove("a/baz"); err != nil {
+		t.Fatalf("remove(a/baz) = %v", err)
+	}
+	if err := o.WriteFile("a/qux", []byte("upper"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := o.ReadDir("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"bar", "qux"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("readdir(a) = %q, want %q", names, want)
+	}
+
+	// Recreating a masked file clears the whiteout.
+	if err := o.WriteFile("foo", []byte("upper foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := o.ReadFile("foo"); err != nil || string(b) != "upper foo" {
+		t.Fatalf("readfile(foo) = %q, %v, want %q", b, err, "upper foo")
+	}
+}
+
+func TestOverlayCleansNames(t *testing.T) {
+	lower := New()
+	if err := lower.Mkdir("d", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "d/b"} {
+		if err := lower.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o := Overlay(New(), lower)
+	if err := o.Remove("./a"); err != nil {
+		t.Fatalf("remove(./a) = %v", err)
+	}
+	if err := o.Remove("d//b"); err != nil {
+		t.Fatalf("remove(d//b) = %v", err)
+	}
+	for _, name := range []string{"a", "./a", "d/../a", "d/b", "./d/b"} {
+		if _, err := o.Stat(name); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("stat(%q) = %v, want %v", name, err, os.ErrNotExist)
+		}
+		if _, err := o.OpenFile(name, os.O_RDONLY, 0); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("openfile(%q) = %v, want %v", name, err, os.ErrNotExist)
+		}
+		if _, err := o.ReadFile(name); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("readfile(%q) = %v, want %v", name, err, os.ErrNotExist)
+		}
+	}
+	if entries, err := o.ReadDir("./d"); err != nil || len(entries) != 0 {
+		t.Fatalf("readdir(./d) = %v, %v, want no entries", entries, err)
+	}
+	if _, err := o.Stat("../a"); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("stat(../a) = %v, want %v", err, os.ErrInvalid)
+	}
+	// Recreating the file through another spelling clears the whiteout.
+	if err := o.WriteFile("./a", []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := o.ReadFile("a"); err != nil || string(b) != "new" {
+		t.Fatalf("readfile(a) = %q, %v, want %q", b, err, "new")
+	}
+}
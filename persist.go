@@ -0,0 +1,166 @@
+package ramfs
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+)
+
+// savedNode is the serialized form of a Node used by Save, Load and the
+// JSON encoding of a Filesystem.
+type savedNode struct {
+	Name       string      `json:"-"`
+	Data       []byte      `json:"data,omitempty"`
+	Mode       os.FileMode `json:"mode"`
+	ModTime    time.Time   `json:"modTime"`
+	AccessTime time.Time   `json:"accessTime"`
+	IsDir      bool        `json:"isDir"`
+	Target     string      `json:"target,omitempty"`
+	Uid        int         `json:"uid,omitempty"`
+	Gid        int         `json:"gid,omitempty"`
+}
+
+// save returns the serialized form of all nodes sorted by name. Hard links
+// are saved as independent files.
+func (fs *Filesystem) save() []savedNode {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	names := make([]string, 0, len(fs.files))
+	for k := range fs.files {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	nodes := make([]savedNode, 0, len(names))
+	for _, name := range names {
+		n := fs.files[name]
+		n.Mu.RLock()
+		n.atimeMu.Lock()
+		nodes = append(nodes, savedNode{
+			Name:       name,
+			Data:       append([]byte(nil), n.Data.Bytes()...),
+			Mode:       n.Mode,
+			ModTime:    n.ModTime,
+			AccessTime: n.AccessTime,
+			IsDir:      n.IsDir,
+			Target:     n.Target,
+			Uid:        n.Uid,
+			Gid:        n.Gid,
+		})
+		n.atimeMu.Unlock()
+		n.Mu.RUnlock()
+	}
+	return nodes
+}
+
+// restore replaces the contents of the filesystem with nodes.
+func (fs *Filesystem) restore(nodes []savedNode) error {
+	files := make(map[string]*Node, len(nodes))
+	var used int64
+	for _, s := range nodes {
+		name, ok := cleanPath(s.Name)
+		if !ok || name == "." {
+			return &os.PathError{
+				Op:   "load",
+				Err:  os.ErrInvalid,
+				Path: s.Name,
+			}
+		}
+		n := fs.newNode(name, s.Mode)
+		n.Data.Write(s.Data)
+		n.ModTime = s.ModTime
+		n.AccessTime = s.AccessTime
+		n.IsDir = s.IsDir
+		n.Target = s.Target
+		n.Uid = s.Uid
+		n.Gid = s.Gid
+		files[name] = n
+		used += int64(len(s.Data))
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.files = make(map[string]*Node, len(files))
+	if fs.folded != nil {
+		fs.folded = make(map[string]string, len(files))
+	}
+	for name, n := range files {
+		fs.put(name, n)
+	}
+	fs.used.Store(used)
+	return nil
+}
+
+// Save writes all files and directories of the filesystem to the host
+// file hostname, which can be read back with Load. Hard links are saved as
+// independent files.
+func (fs *Filesystem) Save(hostname string) error {
+	nodes := fs.save()
+	f, err := os.Create(hostname)
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(nodes); err != nil {
+		f.Close()
+		return &os.PathError{
+			Op:   "save",
+			Err:  err,
+			Path: hostname,
+		}
+	}
+	return f.Close()
+}
+
+// Load reads a filesystem written by Save from the host file hostname.
+func Load(hostname string) (*Filesystem, error) {
+	f, err := os.Open(hostname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var nodes []savedNode
+	if err := gob.NewDecoder(f).Decode(&nodes); err != nil {
+		return nil, &os.PathError{
+			Op:   "load",
+			Err:  err,
+			Path: hostname,
+		}
+	}
+	fs := New()
+	if err := fs.restore(nodes); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// MarshalJSON encodes the filesystem as a JSON object mapping the name of
+// every file and directory to its mode, times and, base64 encoded, data.
+// Hard links are encoded as independent files.
+func (fs *Filesystem) MarshalJSON() ([]byte, error) {
+	nodes := fs.save()
+	m := make(map[string]savedNode, len(nodes))
+	for _, n := range nodes {
+		m[n.Name] = n
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON replaces the contents of the filesystem with the files
+// and directories encoded by MarshalJSON. It may be called on the zero
+// Filesystem.
+func (fs *Filesystem) UnmarshalJSON(b []byte) error {
+	var m map[string]savedNode
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	nodes := make([]savedNode, 0, len(m))
+	for name, n := range m {
+		n.Name = name
+		nodes = append(nodes, n)
+	}
+	if fs.root == nil {
+		fs.clock = realClock{}
+		fs.root = fs.newNode(".", os.ModeDir|0755)
+	}
+	return fs.restore(nodes)
+}
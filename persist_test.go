@@ -0,0 +1,115 @@
+package ramfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSaveLoad(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/b", 0750); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"foo":     []byte("hello"),
+		"a/bin":   {0, 1, 2, 0xff},
+		"a/b/baz": nil,
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	for name, data := range files {
+		if err := fs.WriteFile(name, data, 0640); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Symlink("foo", "link"); err != nil {
+		t.Fatal(err)
+	}
+	hostname := filepath.Join(t.TempDir(), "fs.gob")
+	if err := fs.Save(hostname); err != nil {
+		t.Fatalf("save() = %v", err)
+	}
+	fs2, err := Load(hostname)
+	if err != nil {
+		t.Fatalf("load() = %v", err)
+	}
+	for name, data := range files {
+		b, err := fs2.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != string(data) {
+			t.Fatalf("readfile(%q) = %q, want %q", name, b, data)
+		}
+		st, err := fs2.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Mode() != 0640 || !st.ModTime().Equal(mtime) {
+			t.Fatalf("stat(%q) = %v %v, want %v %v", name, st.Mode(), st.ModTime(), 0640, mtime)
+		}
+	}
+	if st, err := fs2.Stat("a/b"); err != nil || st.Mode() != os.ModeDir|0750 {
+		t.Fatalf("stat(a/b) = %v, %v, want mode %v", st, err, os.ModeDir|0750)
+	}
+	if target, err := fs2.Readlink("link"); err != nil || target != "foo" {
+		t.Fatalf("readlink(link) = %q, %v, want %q", target, err, "foo")
+	}
+	files1, bytes1 := fs.Usage()
+	if files2, bytes2 := fs2.Usage(); files1 != files2 || bytes1 != bytes2 {
+		t.Fatalf("usage() = %d, %d, want %d, %d", files2, bytes2, files1, bytes1)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"a/text": []byte("hello\n"),
+		"a/bin":  {0, 1, 2, 0xfe, 0xff},
+	}
+	for name, data := range files {
+		if err := fs.WriteFile(name, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := json.Marshal(fs)
+	if err != nil {
+		t.Fatalf("marshal() = %v", err)
+	}
+	var fs2 Filesystem
+	if err := json.Unmarshal(b, &fs2); err != nil {
+		t.Fatalf("unmarshal() = %v", err)
+	}
+	// Marshaling is deterministic.
+	b2, err := json.Marshal(&fs2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, b2) {
+		t.Fatalf("marshal() = %s after round trip, want %s", b2, b)
+	}
+	for name, data := range files {
+		got, err := fs2.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Fatalf("readfile(%q) = %q, want %q", name, got, data)
+		}
+		if st, err := fs2.Stat(name); err != nil || st.Mode() != 0600 {
+			t.Fatalf("stat(%q) = %v, %v, want mode %v", name, st, err, os.FileMode(0600))
+		}
+	}
+	if st, err := fs2.Stat("a"); err != nil || !st.IsDir() {
+		t.Fatalf("stat(a) = %v, %v, want directory", st, err)
+	}
+}
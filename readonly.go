@@ -0,0 +1,117 @@
+package ramfs
+
+import (
+	iofs "io/fs"
+	"os"
+	"time"
+)
+
+// ReadOnlyFS is a view of a Filesystem that passes reads through to the
+// underlying filesystem and rejects every modification with
+// os.ErrPermission.
+type ReadOnlyFS struct {
+	fs *Filesystem
+}
+
+// ReadOnly returns a read-only view of the filesystem.
+func (fs *Filesystem) ReadOnly() *ReadOnlyFS {
+	return &ReadOnlyFS{
+		fs: fs,
+	}
+}
+
+func errReadOnly(op, name string) error {
+	return &os.PathError{
+		Op:   op,
+		Err:  os.ErrPermission,
+		Path: name,
+	}
+}
+
+// Open opens the named file for reading.
+func (r *ReadOnlyFS) Open(name string) (iofs.File, error) {
+	return r.fs.Open(name)
+}
+
+// OpenFile opens the named file. Any flag other than O_RDONLY is rejected.
+func (r *ReadOnlyFS) OpenFile(name string, flag int, perm os.FileMode) (*File, error) {
+	if flag != os.O_RDONLY {
+		return nil, errReadOnly("open", name)
+	}
+	return r.fs.OpenFile(name, flag, perm)
+}
+
+// Stat returns a FileInfo describing the named file.
+func (r *ReadOnlyFS) Stat(name string) (os.FileInfo, error) {
+	return r.fs.Stat(name)
+}
+
+// ReadDir reads the named directory.
+func (r *ReadOnlyFS) ReadDir(name string) ([]iofs.DirEntry, error) {
+	return r.fs.ReadDir(name)
+}
+
+// ReadFile reads the named file.
+func (r *ReadOnlyFS) ReadFile(name string) ([]byte, error) {
+	return r.fs.ReadFile(name)
+}
+
+// Glob returns the names of all files matching pattern.
+func (r *ReadOnlyFS) Glob(pattern string) ([]string, error) {
+	return r.fs.Glob(pattern)
+}
+
+// WalkDir walks the file tree rooted at root.
+func (r *ReadOnlyFS) WalkDir(root string, fn iofs.WalkDirFunc) error {
+	return r.fs.WalkDir(root, fn)
+}
+
+// Create is rejected.
+func (r *ReadOnlyFS) Create(name string) (*File, error) {
+	return nil, errReadOnly("open", name)
+}
+
+// WriteFile is rejected.
+func (r *ReadOnlyFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return errReadOnly("open", name)
+}
+
+// Mkdir is rejected.
+func (r *ReadOnlyFS) Mkdir(name string, perm os.FileMode) error {
+	return errReadOnly("mkdir", name)
+}
+
+// MkdirAll is rejected.
+func (r *ReadOnlyFS) MkdirAll(name string, perm os.FileMode) error {
+	return errReadOnly("mkdir", name)
+}
+
+// Chmod is rejected.
+func (r *ReadOnlyFS) Chmod(name string, mode os.FileMode) error {
+	return errReadOnly("chmod", name)
+}
+
+// Chtimes is rejected.
+func (r *ReadOnlyFS) Chtimes(name string, atime, mtime time.Time) error {
+	return errReadOnly("chtimes", name)
+}
+
+// Truncate is rejected.
+func (r *ReadOnlyFS) Truncate(name string, size int64) error {
+	return errReadOnly("truncate", name)
+}
+
+// Remove is rejected.
+func (r *ReadOnlyFS) Remove(name string) error {
+	return errReadOnly("remove", name)
+}
+
+// Rename is rejected.
+func (r *ReadOnlyFS) Rename(oldpath, newpath string) error {
+	return &os.LinkError{
+		Op:  "rename",
+		Old: oldpath,
+		New: newpath,
+		Err: os.ErrPermission,
+	}
+}
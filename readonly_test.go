@@ -0,0 +1,65 @@
+package ramfs
+
+import (
+	"errors"
+	iofs "io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestReadOnly(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("a/foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := fs.ReadOnly()
+	mutations := map[string]func() error{
+		"openfile": func() error { _, err := r.OpenFile("a/foo", os.O_RDWR, 0); return err },
+		"create":   func() error { _, err := r.Create("bar"); return err },
+		"writefile": func() error {
+			return r.WriteFile("a/foo", nil, 0644)
+		},
+		"mkdir":    func() error { return r.Mkdir("b", 0755) },
+		"mkdirall": func() error { return r.MkdirAll("b/c", 0755) },
+		"chmod":    func() error { return r.Chmod("a/foo", 0600) },
+		"chtimes":  func() error { return r.Chtimes("a/foo", time.Time{}, time.Time{}) },
+		"truncate": func() error { return r.Truncate("a/foo", 0) },
+		"remove":   func() error { return r.Remove("a/foo") },
+		"rename":   func() error { return r.Rename("a/foo", "bar") },
+		"file.truncate": func() error {
+			f, err := r.OpenFile("a/foo", os.O_RDONLY, 0)
+			if err != nil {
+				return err
+			}
+			return f.Truncate(0)
+		},
+	}
+	for name, fn := range mutations {
+		if err := fn(); !errors.Is(err, os.ErrPermission) {
+			t.Errorf("%s() = %v, want %v", name, err, os.ErrPermission)
+		}
+	}
+	if b, err := r.ReadFile("a/foo"); err != nil || string(b) != "hello" {
+		t.Fatalf("readfile(a/foo) = %q, %v, want %q", b, err, "hello")
+	}
+	if _, err := r.Stat("a/foo"); err != nil {
+		t.Fatalf("stat(a/foo) = %v", err)
+	}
+	if _, err := r.ReadDir("a"); err != nil {
+		t.Fatalf("readdir(a) = %v", err)
+	}
+	if m, err := r.Glob("a/*"); err != nil || len(m) != 1 {
+		t.Fatalf("glob(a/*) = %q, %v, want 1 match", m, err)
+	}
+	if err := r.WalkDir(".", func(string, iofs.DirEntry, error) error { return nil }); err != nil {
+		t.Fatalf("walkdir() = %v", err)
+	}
+	if err := fstest.TestFS(r, "a/foo"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,40 @@
+package ramfs
+
+import (
+	"os"
+)
+
+// CreateRing creates the named file as a ring buffer holding at most
+// capacity bytes, truncating it if it already exists. Writes to a ring
+// buffer always append; once the data exceeds the capacity, the oldest
+// bytes are dropped. Reads return the bytes currently held. The returned
+// File is opened for reading and writing.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) CreateRing(name string, capacity int) (*File, error) {
+	if capacity <= 0 {
+		return nil, &os.PathError{
+			Op:   "createring",
+			Err:  os.ErrInvalid,
+			Path: name,
+		}
+	}
+	f, err := fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	f.node.Mu.Lock()
+	f.node.ring = capacity
+	f.node.Mu.Unlock()
+	return f, nil
+}
+
+// trim drops the oldest bytes of a ring buffer that exceed its capacity.
+// The caller must hold n.Mu.
+func (n *Node) trim() {
+	if n.ring <= 0 || n.Data.Len() <= n.ring {
+		return
+	}
+	drop := n.Data.Len() - n.ring
+	n.Data.Next(drop)
+	n.grow(-drop)
+}
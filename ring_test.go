@@ -0,0 +1,54 @@
+package ramfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	fs := New()
+	f, err := fs.CreateRing("log", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"hello", " ", "world", "!"} {
+		if _, err := f.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b, err := fs.ReadFile("log"); err != nil || string(b) != "o world!" {
+		t.Fatalf("readfile(log) = %q, %v, want %q", b, err, "o world!")
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := io.ReadAll(f); err != nil || string(b) != "o world!" {
+		t.Fatalf("read() = %q, %v, want %q", b, err, "o world!")
+	}
+	if _, err := f.ReadFrom(strings.NewReader("0123456789")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fs.ReadFile("log"); err != nil || string(b) != "23456789" {
+		t.Fatalf("readfile(log) = %q, %v, want %q", b, err, "23456789")
+	}
+	// Other handles append as well.
+	g, err := fs.OpenFile("log", os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Write([]byte("ab")); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := fs.ReadFile("log"); err != nil || string(b) != "456789ab" {
+		t.Fatalf("readfile(log) = %q, %v, want %q", b, err, "456789ab")
+	}
+	if _, bytes := fs.Usage(); bytes != 8 {
+		t.Fatalf("usage() = %d bytes, want %d", bytes, 8)
+	}
+	if _, err := fs.CreateRing("bad", 0); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("createring(bad, 0) = %v, want %v", err, os.ErrInvalid)
+	}
+}
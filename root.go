@@ -0,0 +1,159 @@
+package ramfs
+
+import (
+	"os"
+	"path"
+	"strings"
+	"syscall"
+)
+
+// Root is a directory of a Filesystem that confines all names passed to
+// its methods to the tree below it, like os.Root. Names that are absolute
+// or escape the directory through ".." elements or symbolic links are
+// rejected with os.ErrInvalid.
+type Root struct {
+	fs   *Filesystem
+	name string
+	// dir is name with symbolic links resolved, in its stored casing.
+	dir string
+}
+
+// OpenRoot opens the directory dir as a Root.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) OpenRoot(dir string) (*Root, error) {
+	dir, err := clean("openroot", dir)
+	if err != nil {
+		return nil, err
+	}
+	return fs.openRoot("openroot", dir)
+}
+
+// openRoot returns a Root for the directory dir, which must be clean.
+// Symbolic links in dir are resolved once, so the root stays at the
+// directory they refer to now.
+func (fs *Filesystem) openRoot(op, dir string) (*Root, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	resolved, err := fs.resolve(dir)
+	if err != nil {
+		return nil, &os.PathError{
+			Op:   op,
+			Err:  err,
+			Path: dir,
+		}
+	}
+	f, ok := fs.node(resolved)
+	if !ok {
+		return nil, &os.PathError{
+			Op:   op,
+			Err:  os.ErrNotExist,
+			Path: dir,
+		}
+	}
+	if !f.IsDir {
+		return nil, &os.PathError{
+			Op:   op,
+			Err:  syscall.ENOTDIR,
+			Path: dir,
+		}
+	}
+	return &Root{
+		fs:   fs,
+		name: dir,
+		dir:  resolved,
+	}, nil
+}
+
+// Name returns the name of the directory of the root.
+func (r *Root) Name() string {
+	return r.name
+}
+
+// Open opens the named file in the root for reading.
+// If there is an error, it will be of type *PathError.
+func (r *Root) Open(name string) (*File, error) {
+	return r.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// Create creates or truncates the named file in the root, like
+// Filesystem.Create.
+// If there is an error, it will be of type *PathError.
+func (r *Root) Create(name string) (*File, error) {
+	return r.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// OpenFile opens the named file in the root, like Filesystem.OpenFile.
+// If there is an error, it will be of type *PathError.
+func (r *Root) OpenFile(name string, flag int, perm os.FileMode) (*File, error) {
+	r.fs.mu.Lock()
+	defer r.fs.mu.Unlock()
+	full, resolved, err := r.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := r.fs.openFile(full, resolved, flag, perm)
+	return f, r.relative(err, name)
+}
+
+// Stat returns a FileInfo describing the named file in the root.
+// If there is an error, it will be of type *PathError.
+func (r *Root) Stat(name string) (os.FileInfo, error) {
+	r.fs.mu.Lock()
+	defer r.fs.mu.Unlock()
+	full, resolved, err := r.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	st, err := r.fs.stat(full, resolved)
+	return st, r.relative(err, name)
+}
+
+// resolve returns the name of the file name in the root relative to the
+// root of the filesystem, and the name of the file it resolves to. It
+// fails if name, or the file a symbolic link in it refers to, is outside
+// of the root. The caller must hold r.fs.mu and keep holding it while
+// using the result, so that the names cannot be redirected in between.
+func (r *Root) resolve(op, name string) (full, resolved string, err error) {
+	cleaned, ok := cleanPath(name)
+	if !ok {
+		return "", "", &os.PathError{
+			Op:   op,
+			Err:  os.ErrInvalid,
+			Path: name,
+		}
+	}
+	full = path.Join(r.name, cleaned)
+	resolved, err = r.fs.resolve(path.Join(r.dir, cleaned))
+	if err != nil {
+		return "", "", &os.PathError{
+			Op:   op,
+			Err:  err,
+			Path: name,
+		}
+	}
+	if !r.contains(resolved) {
+		return "", "", &os.PathError{
+			Op:   op,
+			Err:  os.ErrInvalid,
+			Path: name,
+		}
+	}
+	return full, resolved, nil
+}
+
+// contains reports whether name is the root or below it.
+func (r *Root) contains(name string) bool {
+	if r.dir == "." {
+		_, ok := cleanPath(name)
+		return ok
+	}
+	return name == r.dir || strings.HasPrefix(name, r.dir+"/")
+}
+
+// relative reports err for name rather than its name in the filesystem.
+func (r *Root) relative(err error, name string) error {
+	if pe, ok := err.(*os.PathError); ok {
+		pe.Path = name
+	}
+	return err
+}
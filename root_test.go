@@ -0,0 +1,166 @@
+package ramfs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestRoot(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("jail/sub", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("secret", []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("jail/sub/foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("sub/foo", "jail/inside"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("../secret", "jail/outside"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("/secret", "jail/absolute"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := fs.OpenRoot("jail")
+	if err != nil {
+		t.Fatalf("openroot(jail) = %v", err)
+	}
+	if r.Name() != "jail" {
+		t.Fatalf("name() = %q, want %q", r.Name(), "jail")
+	}
+	for _, name := range []string{"sub/foo", "sub/../sub/foo", "./sub/foo", "inside"} {
+		f, err := r.Open(name)
+		if err != nil {
+			t.Fatalf("open(%q) = %v", name, err)
+		}
+		b, err := io.ReadAll(f)
+		f.Close()
+		if err != nil || string(b) != "foo" {
+			t.Fatalf("readall(%q) = %q, %v, want %q", name, b, err, "foo")
+		}
+	}
+	f, err := r.Create("bar")
+	if err != nil {
+		t.Fatalf("create(bar) = %v", err)
+	}
+	f.Close()
+	if _, err := fs.Stat("jail/bar"); err != nil {
+		t.Fatalf("stat(jail/bar) = %v, want nil", err)
+	}
+	if st, err := r.Stat("sub"); err != nil || !st.IsDir() {
+		t.Fatalf("stat(sub) = %v, %v, want directory", st, err)
+	}
+	for _, name := range []string{"..", "../secret", "sub/../../secret", "/secret", "outside", "absolute"} {
+		if _, err := r.Open(name); !errors.Is(err, os.ErrInvalid) {
+			t.Errorf("open(%q) = %v, want %v", name, err, os.ErrInvalid)
+		}
+		if _, err := r.Stat(name); !errors.Is(err, os.ErrInvalid) {
+			t.Errorf("stat(%q) = %v, want %v", name, err, os.ErrInvalid)
+		}
+	}
+	if _, err := r.Create("../evil"); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("create(../evil) = %v, want %v", err, os.ErrInvalid)
+	}
+	if fs.Exists("evil") {
+		t.Fatalf("create(../evil) created evil")
+	}
+	var pe *os.PathError
+	if _, err := r.Open("missing"); !errors.As(err, &pe) || pe.Path != "missing" || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("open(missing) = %v, want *PathError for missing with %v", err, os.ErrNotExist)
+	}
+	if _, err := fs.OpenRoot("secret"); !errors.Is(err, syscall.ENOTDIR) {
+		t.Fatalf("openroot(secret) = %v, want %v", err, syscall.ENOTDIR)
+	}
+	if _, err := fs.OpenRoot("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("openroot(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestRootSymlinkRace(t *testing.T) {
+	fs := New()
+	for _, dir := range []string{"jail/in", "out"} {
+		if err := fs.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.WriteFile(dir+"/foo", []byte(dir), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Symlink("in", "jail/l"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := fs.OpenRoot("jail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 1000; i++ {
+			target := "in"
+			if i%2 == 0 {
+				target = "../out"
+			}
+			fs.Remove("jail/l")
+			fs.Symlink(target, "jail/l")
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		f, err := r.Open("l/foo")
+		if err != nil {
+			continue
+		}
+		b, err := io.ReadAll(f)
+		f.Close()
+		if err != nil || string(b) != "jail/in" {
+			t.Fatalf("readall(l/foo) = %q, %v, want %q", b, err, "jail/in")
+		}
+	}
+}
+
+func TestRootResolvesDir(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("real", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("real/foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("real", "link"); err != nil {
+		t.Fatal(err)
+	}
+	r, err := fs.OpenRoot("link")
+	if err != nil {
+		t.Fatalf("openroot(link) = %v", err)
+	}
+	if st, err := r.Stat("foo"); err != nil || st.Size() != 3 {
+		t.Fatalf("stat(foo) = %v, %v, want size 3", st, err)
+	}
+
+	fs = NewCaseInsensitive()
+	if err := fs.MkdirAll("Dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("Dir/foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r, err = fs.OpenRoot("dir")
+	if err != nil {
+		t.Fatalf("openroot(dir) = %v", err)
+	}
+	if st, err := r.Stat("FOO"); err != nil || st.Size() != 3 {
+		t.Fatalf("stat(FOO) = %v, %v, want size 3", st, err)
+	}
+}
@@ -0,0 +1,45 @@
+package ramfs
+
+import (
+	iofs "io/fs"
+	"os"
+)
+
+// subFS is a view of a Filesystem rooted at a directory.
+type subFS struct {
+	root *Root
+}
+
+// Sub returns an io/fs.FS corresponding to the subtree rooted at dir.
+// Names that would escape dir, also through symbolic links, are rejected.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Sub(dir string) (iofs.FS, error) {
+	if !iofs.ValidPath(dir) {
+		return nil, &os.PathError{
+			Op:   "sub",
+			Err:  os.ErrInvalid,
+			Path: dir,
+		}
+	}
+	root, err := fs.openRoot("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+	return &subFS{root: root}, nil
+}
+
+// Open opens the named file relative to the root of the view.
+func (s *subFS) Open(name string) (iofs.File, error) {
+	if !iofs.ValidPath(name) {
+		return nil, &os.PathError{
+			Op:   "open",
+			Err:  os.ErrInvalid,
+			Path: name,
+		}
+	}
+	f, err := s.root.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
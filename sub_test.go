@@ -0,0 +1,128 @@
+package ramfs
+
+import (
+	"errors"
+	"io"
+	iofs "io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSub(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/b", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "a/bar", "a/b/baz"} {
+		if err := fs.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sub, err := fs.Sub("a")
+	if err != nil {
+		t.Fatalf("sub(a) = %v", err)
+	}
+	fd, err := sub.Open("b/baz")
+	if err != nil {
+		t.Fatalf("open(b/baz) = %v", err)
+	}
+	b, err := io.ReadAll(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "a/b/baz" {
+		t.Fatalf("read(b/baz) = %q, want %q", b, "a/b/baz")
+	}
+	for _, name := range []string{"../foo", "b/../../foo", "/foo"} {
+		if _, err := sub.Open(name); !errors.Is(err, os.ErrInvalid) {
+			t.Errorf("open(%q) = %v, want %v", name, err, os.ErrInvalid)
+		}
+	}
+	if _, err := fs.Sub("../a"); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("sub(../a) = %v, want %v", err, os.ErrInvalid)
+	}
+	if _, err := fs.Sub("foo"); err == nil {
+		t.Fatalf("sub(foo) = nil, want error")
+	}
+	if err := fstest.TestFS(sub, "bar", "b/baz"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubSymlinkEscape(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("top/in", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"secret", "top/in/foo"} {
+		if err := fs.WriteFile(name, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		"top/in/l":   "../../secret",
+		"top/abs":    "/secret",
+		"top/in/ok":  "foo",
+		"top/inside": "/top/in/foo",
+	} {
+		if err := fs.Symlink(target, link); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sub, err := fs.Sub("top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"in/l", "abs"} {
+		if _, err := sub.Open(name); !errors.Is(err, os.ErrInvalid) {
+			t.Errorf("open(%s) = %v, want %v", name, err, os.ErrInvalid)
+		}
+	}
+	for _, name := range []string{"in/ok", "inside"} {
+		f, err := sub.Open(name)
+		if err != nil {
+			t.Fatalf("open(%s) = %v", name, err)
+		}
+		b, err := io.ReadAll(f)
+		f.Close()
+		if err != nil || string(b) != "top/in/foo" {
+			t.Fatalf("readall(%s) = %q, %v, want %q", name, b, err, "top/in/foo")
+		}
+	}
+}
+
+func TestSubResolvesDir(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("real", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("real/foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("real", "link"); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := fs.Sub("link")
+	if err != nil {
+		t.Fatalf("sub(link) = %v", err)
+	}
+	if b, err := iofs.ReadFile(sub, "foo"); err != nil || string(b) != "foo" {
+		t.Fatalf("readfile(foo) = %q, %v, want %q", b, err, "foo")
+	}
+
+	fs = NewCaseInsensitive()
+	if err := fs.Mkdir("Dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("Dir/foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sub, err = fs.Sub("dir")
+	if err != nil {
+		t.Fatalf("sub(dir) = %v", err)
+	}
+	if b, err := iofs.ReadFile(sub, "foo"); err != nil || string(b) != "foo" {
+		t.Fatalf("readfile(foo) = %q, %v, want %q", b, err, "foo")
+	}
+}
@@ -0,0 +1,183 @@
+package ramfs
+
+import (
+	"os"
+	"path"
+	"strings"
+	"syscall"
+)
+
+// maxSymlinks is the maximum number of symbolic links followed while
+// resolving a single name.
+const maxSymlinks = 40
+
+// Symlink creates newname as a symbolic link to oldname.
+// If there is an error, it will be of type *LinkError.
+func (fs *Filesystem) Symlink(oldname, newname string) error {
+	name, ok := cleanPath(newname)
+	if !ok {
+		return &os.LinkError{
+			Op:  "symlink",
+			Old: oldname,
+			New: newname,
+			Err: os.ErrInvalid,
+		}
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	name, err := fs.resolveParent(name)
+	if err != nil {
+		return &os.LinkError{
+			Op:  "symlink",
+			Old: oldname,
+			New: newname,
+			Err: err,
+		}
+	}
+	if _, ok := fs.node(name); ok {
+		return &os.LinkError{
+			Op:  "symlink",
+			Old: oldname,
+			New: newname,
+			Err: os.ErrExist,
+		}
+	}
+	parent, ok := fs.node(path.Dir(name))
+	if !ok {
+		return &os.LinkError{
+			Op:  "symlink",
+			Old: oldname,
+			New: newname,
+			Err: os.ErrNotExist,
+		}
+	}
+	if !parent.IsDir {
+		return &os.LinkError{
+			Op:  "symlink",
+			Old: oldname,
+			New: newname,
+			Err: syscall.ENOTDIR,
+		}
+	}
+	f := fs.newNode(name, os.ModeSymlink|0777)
+	f.Target = oldname
+	fs.put(name, f)
+	fs.emit(name, Create)
+	return nil
+}
+
+// Readlink returns the destination of the named symbolic link.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Readlink(name string) (string, error) {
+	name, err := clean("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	resolved, err := fs.resolveParent(name)
+	if err != nil {
+		return "", &os.PathError{
+			Op:   "readlink",
+			Err:  err,
+			Path: name,
+		}
+	}
+	f, ok := fs.node(resolved)
+	if !ok {
+		return "", &os.PathError{
+			Op:   "readlink",
+			Err:  os.ErrNotExist,
+			Path: name,
+		}
+	}
+	if f.Mode&os.ModeSymlink == 0 {
+		return "", &os.PathError{
+			Op:   "readlink",
+			Err:  os.ErrInvalid,
+			Path: name,
+		}
+	}
+	return f.Target, nil
+}
+
+// Lstat returns a FileInfo describing the named file. If the file is a
+// symbolic link, the returned FileInfo describes the link itself and
+// Lstat makes no attempt to follow it.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Lstat(name string) (os.FileInfo, error) {
+	name, err := clean("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	resolved, err := fs.resolveParent(name)
+	if err != nil {
+		return nil, &os.PathError{
+			Op:   "lstat",
+			Err:  err,
+			Path: name,
+		}
+	}
+	f, ok := fs.node(resolved)
+	if !ok {
+		return nil, &os.PathError{
+			Op:   "lstat",
+			Err:  os.ErrNotExist,
+			Path: name,
+		}
+	}
+	return f.stat(resolved), nil
+}
+
+// Lchmod changes the mode of the named file to mode, using the same bits
+// of mode as Chmod. If the file is a symbolic link, it changes the mode of
+// the link itself.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) Lchmod(name string, mode os.FileMode) error {
+	return fs.chmod("lchmod", name, mode, fs.resolveParent)
+}
+
+// resolve follows all symbolic links in name and returns the name of the
+// file it refers to. Relative link targets are interpreted relative to
+// the directory containing the link, absolute ones relative to the root
+// of the filesystem. The caller must hold fs.mu.
+func (fs *Filesystem) resolve(name string) (string, error) {
+	hops := 0
+	elems := strings.Split(name, "/")
+	cur := "."
+	for i := 0; i < len(elems); i++ {
+		next := fs.canon(path.Join(cur, elems[i]))
+		f, ok := fs.files[next]
+		if !ok || f.Mode&os.ModeSymlink == 0 {
+			cur = next
+			continue
+		}
+		hops++
+		if hops > maxSymlinks {
+			return "", syscall.ELOOP
+		}
+		target := path.Join(cur, f.Target)
+		if path.IsAbs(f.Target) {
+			target = path.Clean(strings.TrimPrefix(f.Target, "/"))
+		}
+		elems = append(strings.Split(target, "/"), elems[i+1:]...)
+		cur = "."
+		i = -1
+	}
+	if hops == 0 && fs.folded == nil {
+		return name, nil
+	}
+	return cur, nil
+}
+
+// resolveParent is like resolve but does not follow a symbolic link in
+// the final element of name. The caller must hold fs.mu.
+func (fs *Filesystem) resolveParent(name string) (string, error) {
+	dir, err := fs.resolve(path.Dir(name))
+	if err != nil {
+		return "", err
+	}
+	return fs.canon(path.Join(dir, path.Base(name))), nil
+}
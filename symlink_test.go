@@ -0,0 +1,237 @@
+package ramfs
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSymlink(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("a/foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"a/rel":  "foo",
+		"abs":    "/a/foo",
+		"dir":    "a",
+		"chain":  "a/rel",
+		"loop1":  "loop2",
+		"loop2":  "loop1",
+		"broken": "missing",
+	}
+	for name, target := range links {
+		if err := fs.Symlink(target, name); err != nil {
+			t.Fatalf("symlink(%q, %q) = %v", target, name, err)
+		}
+	}
+	for name, target := range links {
+		got, err := fs.Readlink(name)
+		if err != nil {
+			t.Fatalf("readlink(%q) = %v", name, err)
+		}
+		if got != target {
+			t.Fatalf("readlink(%q) = %q, want %q", name, got, target)
+		}
+	}
+	for _, name := range []string{"a/rel", "abs", "dir/foo", "chain", "dir/rel"} {
+		b, err := fs.ReadFile(name)
+		if err != nil {
+			t.Fatalf("readfile(%q) = %v", name, err)
+		}
+		if string(b) != "hello" {
+			t.Fatalf("readfile(%q) = %q, want %q", name, b, "hello")
+		}
+	}
+	if st, err := fs.Stat("a/rel"); err != nil || st.Mode() != 0644 {
+		t.Fatalf("stat(a/rel) = %v, %v, want mode %v", st, err, os.FileMode(0644))
+	}
+	if _, err := fs.Open("loop1"); !errors.Is(err, syscall.ELOOP) {
+		t.Fatalf("open(loop1) = %v, want %v", err, syscall.ELOOP)
+	}
+	if _, err := fs.Open("broken"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("open(broken) = %v, want %v", err, os.ErrNotExist)
+	}
+	if err := fs.Symlink("foo", "a/rel"); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("symlink(foo, a/rel) = %v, want %v", err, os.ErrExist)
+	}
+	if _, err := fs.Readlink("a/foo"); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("readlink(a/foo) = %v, want %v", err, os.ErrInvalid)
+	}
+}
+
+func TestSymlinkTar(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("foo", "link"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := fs.WriteTar(&buf); err != nil {
+		t.Fatal(err)
+	}
+	fs2 := New()
+	if err := fs2.ReadTar(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	if target, err := fs2.Readlink("link"); err != nil || target != "foo" {
+		t.Fatalf("readlink(link) = %q, %v, want %q", target, err, "foo")
+	}
+	buf.Reset()
+	if err := fs.WriteZip(&buf); err != nil {
+		t.Fatal(err)
+	}
+	fs3 := New()
+	if err := fs3.ReadZip(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatal(err)
+	}
+	if target, err := fs3.Readlink("link"); err != nil || target != "foo" {
+		t.Fatalf("readlink(link) = %q, %v, want %q", target, err, "foo")
+	}
+}
+
+func TestLstat(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("foo", "link"); err != nil {
+		t.Fatal(err)
+	}
+	st, err := fs.Stat("link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode() != 0644 || st.Size() != 11 || st.Name() != "link" {
+		t.Fatalf("stat(link) = %v %v %v, want %v %v %v", st.Mode(), st.Size(), st.Name(), os.FileMode(0644), 11, "link")
+	}
+	lst, err := fs.Lstat("link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lst.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("lstat(link).Mode() = %v, want symlink", lst.Mode())
+	}
+	if lst.Size() != 3 || lst.Name() != "link" {
+		t.Fatalf("lstat(link) = %v %v, want %v %v", lst.Size(), lst.Name(), 3, "link")
+	}
+	if lst, err := fs.Lstat("foo"); err != nil || lst.Size() != 11 {
+		t.Fatalf("lstat(foo) = %v, %v, want size %v", lst, err, 11)
+	}
+	if _, err := fs.Lstat("missing"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("lstat(missing) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestChmodSymlink(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("foo", "link"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Chmod("link", 0600); err != nil {
+		t.Fatalf("chmod(link) = %v", err)
+	}
+	if st, err := fs.Stat("foo"); err != nil || st.Mode() != 0600 {
+		t.Fatalf("stat(foo) = %v, %v, want mode %v", st, err, os.FileMode(0600))
+	}
+	lst, err := fs.Lstat("link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	linkMode := lst.Mode()
+	if linkMode&os.ModeSymlink == 0 {
+		t.Fatalf("lstat(link).Mode() = %v after chmod, want symlink", linkMode)
+	}
+	if err := fs.Lchmod("link", os.ModeSymlink|0700); err != nil {
+		t.Fatalf("lchmod(link) = %v", err)
+	}
+	if lst, err := fs.Lstat("link"); err != nil || lst.Mode() != os.ModeSymlink|0700 {
+		t.Fatalf("lstat(link) = %v, %v, want mode %v", lst, err, os.ModeSymlink|0700)
+	}
+	if st, err := fs.Stat("foo"); err != nil || st.Mode() != 0600 {
+		t.Fatalf("stat(foo) = %v, %v after lchmod, want mode %v", st, err, os.FileMode(0600))
+	}
+	if err := fs.Symlink("missing", "dangling"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Chmod("dangling", 0600); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("chmod(dangling) = %v, want %v", err, os.ErrNotExist)
+	}
+	if err := fs.Lchmod("dangling", os.ModeSymlink|0600); err != nil {
+		t.Fatalf("lchmod(dangling) = %v", err)
+	}
+}
+
+func TestSymlinkParent(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("d", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("d/f", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("d", "l"); err != nil {
+		t.Fatal(err)
+	}
+	if !fs.Exists("l/f") {
+		t.Fatalf("exists(l/f) = false, want true")
+	}
+	if err := fs.Chown("l/f", 1, 2); err != nil {
+		t.Fatalf("chown(l/f) = %v", err)
+	}
+	if err := fs.Chtimes("l/f", time.Time{}, time.Unix(1, 0)); err != nil {
+		t.Fatalf("chtimes(l/f) = %v", err)
+	}
+	if st, err := fs.Stat("d/f"); err != nil || !st.ModTime().Equal(time.Unix(1, 0)) {
+		t.Fatalf("stat(d/f) = %v, %v, want mtime %v", st, err, time.Unix(1, 0))
+	}
+	if err := fs.Remove("l/f"); err != nil {
+		t.Fatalf("remove(l/f) = %v", err)
+	}
+	if fs.Exists("d/f") {
+		t.Fatalf("exists(d/f) = true after remove(l/f), want false")
+	}
+	if err := fs.Mkdir("l/sub", 0755); err != nil {
+		t.Fatalf("mkdir(l/sub) = %v", err)
+	}
+	if err := fs.MkdirAll("l/a/b", 0755); err != nil {
+		t.Fatalf("mkdirall(l/a/b) = %v", err)
+	}
+	if err := fs.MkdirAll("l", 0755); err != nil {
+		t.Fatalf("mkdirall(l) = %v", err)
+	}
+	if err := fs.WriteFile("l/y", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Rename("l/y", "l/sub/z"); err != nil {
+		t.Fatalf("rename(l/y, l/sub/z) = %v", err)
+	}
+	if err := fs.Move("l/sub/z", "l/a/z"); err != nil {
+		t.Fatalf("move(l/sub/z, l/a/z) = %v", err)
+	}
+	for _, name := range []string{"d/sub", "d/a/b", "d/a/z"} {
+		if !fs.Exists(name) {
+			t.Fatalf("exists(%s) = false, want true", name)
+		}
+	}
+	if st, err := fs.Lstat("l"); err != nil || st.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("lstat(l) = %v, %v, want symlink", st, err)
+	}
+	if err := fs.RemoveAll("l"); err != nil {
+		t.Fatalf("removeall(l) = %v", err)
+	}
+	if !fs.Exists("d") {
+		t.Fatalf("exists(d) = false after removeall(l), want true")
+	}
+}
@@ -0,0 +1,156 @@
+package ramfs
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// WriteTar writes the contents of the filesystem as a tar archive to w.
+// Directories are written before their children.
+func (fs *Filesystem) WriteTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := fs.WalkDir(".", func(name string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = fs.Readlink(name); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if sys, ok := info.Sys().(*SysInfo); ok {
+			hdr.Uid = sys.Uid
+			hdr.Gid = sys.Gid
+		}
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// ReadTar reads a tar archive from r and adds its directories, symbolic
+// links and regular files to the filesystem, preserving their mode,
+// ownership and modification time. Other entry types, such as hard links or devices, are
+// skipped unless strict is set, in which case they are reported as an
+// error.
+func (fs *Filesystem) ReadTar(r io.Reader, strict bool) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(hdr.Name)
+		if name == "." || !iofs.ValidPath(name) {
+			return &os.PathError{
+				Op:   "readtar",
+				Err:  os.ErrInvalid,
+				Path: hdr.Name,
+			}
+		}
+		var data io.Reader = tr
+		switch hdr.Typeflag {
+		case tar.TypeDir, tar.TypeReg:
+		case tar.TypeSymlink:
+			data = strings.NewReader(hdr.Linkname)
+		default:
+			if strict {
+				return &os.PathError{
+					Op:   "readtar",
+					Err:  errors.ErrUnsupported,
+					Path: hdr.Name,
+				}
+			}
+			continue
+		}
+		atime := hdr.AccessTime
+		if atime.IsZero() {
+			atime = hdr.ModTime
+		}
+		if err := fs.extract(name, hdr.FileInfo(), atime, data); err != nil {
+			return err
+		}
+		if err := fs.lchown(name, hdr.Uid, hdr.Gid); err != nil {
+			return err
+		}
+	}
+}
+
+// extract creates the directory, symbolic link or regular file name
+// described by info, reading the file contents or link target from r, and
+// applies the mode and times of info. Missing parent directories are
+// created.
+func (fs *Filesystem) extract(name string, info os.FileInfo, atime time.Time, r io.Reader) error {
+	if info.IsDir() {
+		if err := fs.MkdirAll(name, info.Mode()&modeBits); err != nil {
+			return err
+		}
+	} else if info.Mode()&os.ModeSymlink != 0 {
+		if err := fs.MkdirAll(path.Dir(name), 0755); err != nil {
+			return err
+		}
+		target, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if err := fs.Symlink(string(target), name); err != nil {
+			return err
+		}
+	} else {
+		if err := fs.MkdirAll(path.Dir(name), 0755); err != nil {
+			return err
+		}
+		f, err := fs.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, r)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if err := fs.Lchmod(name, info.Mode()); err != nil {
+		return err
+	}
+	return fs.lchtimes(name, atime, info.ModTime())
+}
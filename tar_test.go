@@ -0,0 +1,248 @@
+package ramfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteTar(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/b", 0750); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"foo":     "foo",
+		"a/bar":   "bar",
+		"a/b/baz": "baz",
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for name, data := range files {
+		if err := fs.WriteFile(name, []byte(data), 0640); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := fs.WriteTar(&buf); err != nil {
+		t.Fatalf("writetar() = %v", err)
+	}
+	tr := tar.NewReader(&buf)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+		if hdr.Typeflag == tar.TypeDir {
+			if hdr.FileInfo().Mode() != os.ModeDir|0750 {
+				t.Errorf("%s: mode = %v, want %v", hdr.Name, hdr.FileInfo().Mode(), os.ModeDir|0750)
+			}
+			continue
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			t.Errorf("%s: typeflag = %v, want %v", hdr.Name, hdr.Typeflag, tar.TypeReg)
+		}
+		if hdr.FileInfo().Mode() != 0640 {
+			t.Errorf("%s: mode = %v, want %v", hdr.Name, hdr.FileInfo().Mode(), os.FileMode(0640))
+		}
+		if !hdr.ModTime.Equal(mtime) {
+			t.Errorf("%s: modtime = %v, want %v", hdr.Name, hdr.ModTime, mtime)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := files[hdr.Name]; string(b) != want || hdr.Size != int64(len(want)) {
+			t.Errorf("%s: data = %q (%d), want %q", hdr.Name, b, hdr.Size, want)
+		}
+	}
+	want := []string{"a/", "a/b/", "a/b/baz", "a/bar", "foo"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("names = %q, want %q", names, want)
+	}
+}
+
+func TestReadTar(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/b", 0750); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, name := range []string{"foo", "a/bar", "a/b/baz"} {
+		if err := fs.WriteFile(name, []byte(name), 0640); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := fs.WriteTar(&buf); err != nil {
+		t.Fatal(err)
+	}
+	fs2 := New()
+	if err := fs2.ReadTar(&buf, true); err != nil {
+		t.Fatalf("readtar() = %v", err)
+	}
+	for _, name := range []string{"a", "a/b", "foo", "a/bar", "a/b/baz"} {
+		st1, err := fs.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st2, err := fs2.Stat(name)
+		if err != nil {
+			t.Fatalf("stat(%q) = %v", name, err)
+		}
+		if st1.Mode() != st2.Mode() || st1.Size() != st2.Size() {
+			t.Errorf("stat(%q) = %v %d, want %v %d", name, st2.Mode(), st2.Size(), st1.Mode(), st1.Size())
+		}
+		if !st1.IsDir() && !st2.ModTime().Equal(st1.ModTime()) {
+			t.Errorf("stat(%q).ModTime() = %v, want %v", name, st2.ModTime(), st1.ModTime())
+		}
+		if st1.IsDir() {
+			continue
+		}
+		b, err := fs2.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != name {
+			t.Errorf("readfile(%q) = %q, want %q", name, b, name)
+		}
+	}
+}
+
+func TestReadTarStrict(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "dev", Typeflag: tar.TypeChar}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := New().ReadTar(bytes.NewReader(buf.Bytes()), false); err != nil {
+		t.Fatalf("readtar(strict=false) = %v", err)
+	}
+	if err := New().ReadTar(bytes.NewReader(buf.Bytes()), true); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("readtar(strict=true) = %v, want %v", err, errors.ErrUnsupported)
+	}
+}
+
+func TestTarOwner(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("foo", []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Chown("foo", 1000, 100); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := fs.WriteTar(&buf); err != nil {
+		t.Fatal(err)
+	}
+	hdr, err := tar.NewReader(bytes.NewReader(buf.Bytes())).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Uid != 1000 || hdr.Gid != 100 {
+		t.Fatalf("uid, gid = %d, %d, want %d, %d", hdr.Uid, hdr.Gid, 1000, 100)
+	}
+	fs2 := New()
+	if err := fs2.ReadTar(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	st, err := fs2.Stat("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sys := st.Sys().(*SysInfo); sys.Uid != 1000 || sys.Gid != 100 {
+		t.Fatalf("uid, gid = %d, %d, want %d, %d", sys.Uid, sys.Gid, 1000, 100)
+	}
+}
+
+func TestTarSpecialBits(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("tmp", os.ModeSticky|0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.WriteFile("tmp/su", []byte("#!/bin/sh"), os.ModeSetuid|0755); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := fs.WriteTar(&buf); err != nil {
+		t.Fatal(err)
+	}
+	fs2 := New()
+	if err := fs2.ReadTar(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]os.FileMode{
+		"tmp":    os.ModeDir | os.ModeSticky | 0777,
+		"tmp/su": os.ModeSetuid | 0755,
+	} {
+		st, err := fs2.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if st.Mode() != want {
+			t.Errorf("stat(%s).Mode() = %v, want %v", name, st.Mode(), want)
+		}
+	}
+}
+
+func TestTarSymlinks(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("b", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := fs.Chtimes("b", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Chown("b", 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	// a sorts before its target, dangling has none.
+	if err := fs.Symlink("b", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("missing", "dangling"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := fs.WriteTar(&buf); err != nil {
+		t.Fatalf("writetar() = %v", err)
+	}
+	fs2 := New()
+	if err := fs2.ReadTar(&buf, true); err != nil {
+		t.Fatalf("readtar() = %v", err)
+	}
+	for name, want := range map[string]string{"a": "b", "dangling": "missing"} {
+		if target, err := fs2.Readlink(name); err != nil || target != want {
+			t.Fatalf("readlink(%q) = %q, %v, want %q", name, target, err, want)
+		}
+	}
+	st, err := fs2.Stat("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.ModTime().Equal(mtime) {
+		t.Fatalf("stat(b).ModTime() = %v, want %v", st.ModTime(), mtime)
+	}
+	if sys := st.Sys().(*SysInfo); sys.Uid != 1 || sys.Gid != 2 {
+		t.Fatalf("stat(b) owner = %d:%d, want 1:2", sys.Uid, sys.Gid)
+	}
+}
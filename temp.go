@@ -0,0 +1,99 @@
+package ramfs
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxTempTries is the number of random names tried before giving up.
+const maxTempTries = 10000
+
+// CreateTemp creates a new temporary file in the directory dir, opens the
+// file for reading and writing, and returns the resulting file. The
+// filename is generated by taking pattern and adding a random string to
+// the end. If pattern includes a "*", the random string replaces the last
+// "*". If dir is the empty string, the root directory is used. The caller
+// can use the file's Name method to find the pathname of the file.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) CreateTemp(dir, pattern string) (*File, error) {
+	prefix, suffix, err := prefixAndSuffix(pattern)
+	if err != nil {
+		return nil, &os.PathError{
+			Op:   "createtemp",
+			Err:  err,
+			Path: pattern,
+		}
+	}
+	if dir == "" {
+		dir = "."
+	}
+	for i := 0; i < maxTempTries; i++ {
+		name := path.Join(dir, prefix+nextRandom()+suffix)
+		f, err := fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		return f, err
+	}
+	return nil, &os.PathError{
+		Op:   "createtemp",
+		Err:  os.ErrExist,
+		Path: path.Join(dir, prefix+"*"+suffix),
+	}
+}
+
+// MkdirTemp creates a new temporary directory in the directory dir and
+// returns the pathname of the new directory. The new directory's name is
+// generated by adding a random string to the end of pattern. If pattern
+// includes a "*", the random string replaces the last "*" instead. If dir
+// is the empty string, the root directory is used.
+// If there is an error, it will be of type *PathError.
+func (fs *Filesystem) MkdirTemp(dir, pattern string) (string, error) {
+	prefix, suffix, err := prefixAndSuffix(pattern)
+	if err != nil {
+		return "", &os.PathError{
+			Op:   "mkdirtemp",
+			Err:  err,
+			Path: pattern,
+		}
+	}
+	if dir == "" {
+		dir = "."
+	}
+	for i := 0; i < maxTempTries; i++ {
+		name := path.Join(dir, prefix+nextRandom()+suffix)
+		err := fs.Mkdir(name, 0700)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return name, nil
+	}
+	return "", &os.PathError{
+		Op:   "mkdirtemp",
+		Err:  os.ErrExist,
+		Path: path.Join(dir, prefix+"*"+suffix),
+	}
+}
+
+// prefixAndSuffix splits pattern by the last wildcard "*".
+func prefixAndSuffix(pattern string) (prefix, suffix string, err error) {
+	if strings.Contains(pattern, "/") {
+		return "", "", os.ErrInvalid
+	}
+	if i := strings.LastIndexByte(pattern, '*'); i >= 0 {
+		return pattern[:i], pattern[i+1:], nil
+	}
+	return pattern, "", nil
+}
+
+// nextRandom returns a random string for a temporary name.
+func nextRandom() string {
+	return strconv.FormatUint(uint64(rand.Uint32()), 10)
+}
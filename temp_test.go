@@ -0,0 +1,88 @@
+package ramfs
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestCreateTemp(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		f, err := fs.CreateTemp("tmp", "foo-*.txt")
+		if err != nil {
+			t.Fatalf("createtemp(tmp, foo-*.txt) = %v", err)
+		}
+		name := f.Name()
+		if seen[name] {
+			t.Fatalf("createtemp(tmp, foo-*.txt) = %q twice", name)
+		}
+		seen[name] = true
+		base := path.Base(name)
+		if path.Dir(name) != "tmp" || !strings.HasPrefix(base, "foo-") || !strings.HasSuffix(base, ".txt") {
+			t.Fatalf("createtemp(tmp, foo-*.txt) = %q, want tmp/foo-*.txt", name)
+		}
+		if _, err := f.Write([]byte(name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name := range seen {
+		if b, err := fs.ReadFile(name); err != nil || string(b) != name {
+			t.Fatalf("readfile(%q) = %q, %v, want %q", name, b, err, name)
+		}
+	}
+	f, err := fs.CreateTemp("", "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := f.Name(); !strings.HasPrefix(name, "bar") || strings.Contains(name, "/") {
+		t.Fatalf("createtemp(\"\", bar) = %q, want bar*", name)
+	}
+	if _, err := fs.CreateTemp("", "a/*"); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("createtemp(\"\", a/*) = %v, want %v", err, os.ErrInvalid)
+	}
+	if _, err := fs.CreateTemp("missing", "*"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("createtemp(missing, *) = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestMkdirTemp(t *testing.T) {
+	fs := New()
+	if err := fs.Mkdir("tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		name, err := fs.MkdirTemp("tmp", "scratch-*")
+		if err != nil {
+			t.Fatalf("mkdirtemp(tmp, scratch-*) = %v", err)
+		}
+		if seen[name] {
+			t.Fatalf("mkdirtemp(tmp, scratch-*) = %q twice", name)
+		}
+		seen[name] = true
+		if path.Dir(name) != "tmp" || !strings.HasPrefix(path.Base(name), "scratch-") {
+			t.Fatalf("mkdirtemp(tmp, scratch-*) = %q, want tmp/scratch-*", name)
+		}
+		st, err := fs.Stat(name)
+		if err != nil || !st.IsDir() {
+			t.Fatalf("stat(%q) = %v, %v, want directory", name, st, err)
+		}
+	}
+	name, err := fs.MkdirTemp("", "")
+	if err != nil || strings.Contains(name, "/") {
+		t.Fatalf("mkdirtemp(\"\", \"\") = %q, %v, want a top-level directory", name, err)
+	}
+	if _, err := fs.MkdirTemp("", "a/*"); !errors.Is(err, os.ErrInvalid) {
+		t.Fatalf("mkdirtemp(\"\", a/*) = %v, want %v", err, os.ErrInvalid)
+	}
+	if _, err := fs.MkdirTemp("missing", "*"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("mkdirtemp(missing, *) = %v, want %v", err, os.ErrNotExist)
+	}
+}
@@ -0,0 +1,131 @@
+package ramfs
+
+import (
+	"strings"
+	"sync"
+)
+
+// Op describes the kind of change reported by an Event.
+type Op uint32
+
+// The kinds of changes reported by Watch.
+const (
+	Create Op = 1 << iota
+	Write
+	Remove
+	Rename
+	Chmod
+)
+
+func (op Op) String() string {
+	var names []string
+	for _, o := range []struct {
+		op   Op
+		name string
+	}{
+		{Create, "CREATE"},
+		{Write, "WRITE"},
+		{Remove, "REMOVE"},
+		{Rename, "RENAME"},
+		{Chmod, "CHMOD"},
+	} {
+		if op&o.op != 0 {
+			names = append(names, o.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Event describes a change to a file in the filesystem.
+type Event struct {
+	Name string
+	Op   Op
+}
+
+// watcher queues the events of a single Watch call until they are
+// received, so that emitting an event never blocks.
+type watcher struct {
+	mu    sync.Mutex
+	queue []Event
+	wake  chan struct{}
+	done  chan struct{}
+	c     chan Event
+}
+
+// Watch subscribes to changes of the filesystem. The returned channel
+// receives an Event after each mutation: files and directories being
+// created, written to, removed, renamed or having their mode, owner or
+// times changed. A rename is reported as a Rename event for the old name
+// followed by a Create event for the new one. Events are queued, so a
+// slow receiver does not block the filesystem. Calling the returned
+// function stops the subscription and closes the channel.
+func (fs *Filesystem) Watch() (<-chan Event, func()) {
+	w := &watcher{
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+		c:    make(chan Event),
+	}
+	fs.watchMu.Lock()
+	if fs.watchers == nil {
+		fs.watchers = make(map[*watcher]bool)
+	}
+	fs.watchers[w] = true
+	fs.watchMu.Unlock()
+	go w.run()
+	var once sync.Once
+	return w.c, func() {
+		once.Do(func() {
+			fs.watchMu.Lock()
+			delete(fs.watchers, w)
+			fs.watchMu.Unlock()
+			close(w.done)
+		})
+	}
+}
+
+// emit reports a change of name to all watchers.
+func (fs *Filesystem) emit(name string, op Op) {
+	fs.watchMu.Lock()
+	defer fs.watchMu.Unlock()
+	for w := range fs.watchers {
+		w.mu.Lock()
+		w.queue = append(w.queue, Event{Name: name, Op: op})
+		w.mu.Unlock()
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// emit reports a change of the node to the watchers of its filesystem.
+func (n *Node) emit(name string, op Op) {
+	if n.fs != nil {
+		n.fs.emit(name, op)
+	}
+}
+
+// run delivers the queued events until the watcher is stopped.
+func (w *watcher) run() {
+	defer close(w.c)
+	for {
+		w.mu.Lock()
+		if len(w.queue) == 0 {
+			w.mu.Unlock()
+			select {
+			case <-w.wake:
+				continue
+			case <-w.done:
+				return
+			}
+		}
+		ev := w.queue[0]
+		w.queue = w.queue[1:]
+		w.mu.Unlock()
+		select {
+		case w.c <- ev:
+		case <-w.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package ramfs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWatch(t *testing.T) {
+	fs := New()
+	events, stop := fs.Watch()
+	f, err := fs.Create("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Chmod("foo", 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Rename("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Remove("bar"); err != nil {
+		t.Fatal(err)
+	}
+	want := []Event{
+		{"foo", Create},
+		// Create truncates the file.
+		{"foo", Write},
+		{"foo", Write},
+		{"foo", Chmod},
+		{"foo", Rename},
+		{"bar", Create},
+		{"bar", Remove},
+	}
+	var got []Event
+	for range want {
+		got = append(got, <-events)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	stop()
+	if err := fs.Mkdir("a", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if ev, ok := <-events; ok {
+		t.Fatalf("event %v after stop, want closed channel", ev)
+	}
+	stop()
+}
+
+func TestOpString(t *testing.T) {
+	if got, want := (Create | Write).String(), "CREATE|WRITE"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
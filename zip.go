@@ -0,0 +1,104 @@
+package ramfs
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	iofs "io/fs"
+	"os"
+	"path"
+)
+
+// WriteZip writes the contents of the filesystem as a zip archive to w.
+// Directories are written before their children.
+func (fs *Filesystem) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	err := fs.WalkDir(".", func(name string, d iofs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		hdr.Name = name
+		if info.IsDir() {
+			hdr.Name += "/"
+		} else {
+			hdr.Method = zip.Deflate
+		}
+		zf, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			// Zip stores the target of a symbolic link as its content.
+			link, err := fs.Readlink(name)
+			if err != nil {
+				return err
+			}
+			_, err = io.WriteString(zf, link)
+			return err
+		}
+		f, err := fs.OpenFile(name, os.O_RDONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(zf, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadZip reads a zip archive of the given size from r and adds its
+// directories, symbolic links and regular files to the filesystem,
+// preserving their mode and modification time. Other entry types are
+// reported as an error.
+func (fs *Filesystem) ReadZip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		name := path.Clean(zf.Name)
+		if name == "." || !iofs.ValidPath(name) {
+			return &os.PathError{
+				Op:   "readzip",
+				Err:  os.ErrInvalid,
+				Path: zf.Name,
+			}
+		}
+		info := zf.FileInfo()
+		if !info.IsDir() && !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+			return &os.PathError{
+				Op:   "readzip",
+				Err:  errors.ErrUnsupported,
+				Path: zf.Name,
+			}
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		err = fs.extract(name, info, zf.Modified, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package ramfs
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestZip(t *testing.T) {
+	fs := New()
+	if err := fs.MkdirAll("a/b", 0750); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"foo":     []byte("hello world"),
+		"a/bar":   {0, 1, 2, 0xff, 0xfe},
+		"a/b/baz": bytes.Repeat([]byte("baz"), 1000),
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for name, data := range files {
+		if err := fs.WriteFile(name, data, 0640); err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Chtimes(name, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fs.Chmod("foo", 0755); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := fs.WriteZip(&buf); err != nil {
+		t.Fatalf("writezip() = %v", err)
+	}
+	fs2 := New()
+	if err := fs2.ReadZip(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatalf("readzip() = %v", err)
+	}
+	for _, name := range []string{"a", "a/b", "foo", "a/bar", "a/b/baz"} {
+		st1, err := fs.Stat(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st2, err := fs2.Stat(name)
+		if err != nil {
+			t.Fatalf("stat(%q) = %v", name, err)
+		}
+		if st1.Mode() != st2.Mode() {
+			t.Errorf("stat(%q).Mode() = %v, want %v", name, st2.Mode(), st1.Mode())
+		}
+		if st1.IsDir() {
+			continue
+		}
+		if !st2.ModTime().Equal(mtime) {
+			t.Errorf("stat(%q).ModTime() = %v, want %v", name, st2.ModTime(), mtime)
+		}
+		b, err := fs2.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, files[name]) {
+			t.Errorf("readfile(%q) = %q, want %q", name, b, files[name])
+		}
+	}
+}
+
+func TestZipSymlinks(t *testing.T) {
+	fs := New()
+	if err := fs.WriteFile("b", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := fs.Chtimes("b", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	// a sorts before its target, dangling has none.
+	if err := fs.Symlink("b", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Symlink("missing", "dangling"); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := fs.WriteZip(&buf); err != nil {
+		t.Fatalf("writezip() = %v", err)
+	}
+	fs2 := New()
+	if err := fs2.ReadZip(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatalf("readzip() = %v", err)
+	}
+	for name, want := range map[string]string{"a": "b", "dangling": "missing"} {
+		if target, err := fs2.Readlink(name); err != nil || target != want {
+			t.Fatalf("readlink(%q) = %q, %v, want %q", name, target, err, want)
+		}
+	}
+	if st, err := fs2.Stat("b"); err != nil || !st.ModTime().Equal(mtime) {
+		t.Fatalf("stat(b) = %v, %v, want mtime %v", st, err, mtime)
+	}
+}